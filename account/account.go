@@ -32,19 +32,32 @@
 // account.go
 // @author Sidharth Mishra
 // @created Fri Mar 30 2018 19:18:42 GMT-0700 (PDT)
-// @last-modified Sat Apr 14 2018 10:58:47 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:35:37 GMT-0700 (PDT)
 //
 
 package account
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 
 	"github.com/sidmishraw/gostm/stm"
 )
 
 // ------------------------------------------------------------------------
 
+func init() {
+	stm.DefaultCodecs.Register("account.state", stm.BinaryEncoding[*state]())
+}
+
+// ErrInsufficientBalance is the reason a withdrawal or a transfer that would overdraw
+// an account is aborted.
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
 // Account is the representation for an account.
 // It is the domain object. We split it into identity and state.
 type Account struct {
@@ -70,6 +83,15 @@ func newAccState(amt int) *state {
 	return s
 }
 
+// isSolvent is the validator of the account's state. It is enforced by the STM on
+// every transaction modifying the account -- an account can never be overdrawn.
+func isSolvent(v stm.Value) error {
+	if v.(*state).amt < 0 {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
 // ------------------------------------------------------------------------
 
 // MakeCopy makes state conform to the stm.Value interface.
@@ -96,7 +118,44 @@ func (s *state) IsEqual(v stm.Value) bool {
 	return false
 }
 
+// MarshalJSON encodes the account's state as JSON, e.g. in the JSON snapshots of the STM.
+func (s *state) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount int `json:"amount"`
+	}{s.amt})
+}
+
+// UnmarshalJSON decodes the account's state encoded by MarshalJSON.
+func (s *state) UnmarshalJSON(data []byte) error {
+	var js struct {
+		Amount int `json:"amount"`
+	}
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	s.amt = js.Amount
+	return nil
+}
+
+// MarshalBinary encodes the account's state compactly, the amount as a varint. It is
+// how the account's state is persisted by the STM, see stm.Open.
+func (s *state) MarshalBinary() ([]byte, error) {
+	return binary.AppendVarint(nil, int64(s.amt)), nil
+}
+
+// UnmarshalBinary decodes the account's state encoded by MarshalBinary.
+func (s *state) UnmarshalBinary(data []byte) error {
+	amt, n := binary.Varint(data)
+	if n <= 0 || n != len(data) {
+		return errors.New("malformed account state")
+	}
+	s.amt = int(amt)
+	return nil
+}
+
 // NewAccount creates a new account for the given name and initial balance.
+// On a persisted STM an account recovered with the name keeps its recovered
+// balance instead, see stm.Open.
 func NewAccount(name string, initialAmt int, stm *stm.STM) *Account {
 	acc := new(Account)
 
@@ -104,11 +163,24 @@ func NewAccount(name string, initialAmt int, stm *stm.STM) *Account {
 	acc.Details.Name = name
 	acc.stm = stm
 
-	acc.state = acc.stm.NewTVar(newAccState(initialAmt))
+	acc.state = acc.stm.NewNamedTVar(name, newAccState(initialAmt), isSolvent)
 
 	return acc
 }
 
+// NewShardedAccount creates a new account for the given name and initial balance in the
+// shard of the name of the sharded STM. The deposits and withdrawals of the account
+// commit on its shard alone, in parallel with the accounts of the other shards, and the
+// transfers to the accounts of other shards are transactions across both shards.
+func NewShardedAccount(name string, initialAmt int, sharded *stm.ShardedSTM) *Account {
+	acc := new(Account)
+	acc.Details = new(details)
+	acc.Details.Name = name
+	acc.state = sharded.NewNamedTVar(name, newAccState(initialAmt), isSolvent)
+	acc.stm, _ = sharded.Shard(acc.state) // created by the sharded STM, always in a shard
+	return acc
+}
+
 // Deposit adds the amount to the account's current balance, resulting in
 // increasing the current balance. It is an operation that modifies the
 // account's state. Hence, it must be delegated to the STM as it is managing
@@ -118,49 +190,105 @@ func (acc *Account) Deposit(amt int) {
 	// STM that is managing the state. This ensures consistency,
 	// and atomicity.
 	//
-	acc.stm.Perform(func(t *stm.Transaction) bool {
-		accState := t.Read(acc.state).(*state)
-
-		accState.amt = accState.amt + amt
-
-		return t.Write(acc.state, accState)
+	// Deposits commute, so concurrent deposits into the same account
+	// don't conflict with each other.
+	//
+	acc.stm.PerformLabelled("deposit", func(t *stm.Transaction) bool {
+		return t.Commute(acc.state, func(v stm.Value) stm.Value {
+			accState := v.(*state)
+			accState.amt = accState.amt + amt
+			return accState
+		})
 	})
 }
 
 // Withdraw removes the amount from the account's current balance, resulting in
 // decrease in the current balance. It is an operation that modifies the
 // account's state. Hence, it must be delegated to the STM as it is managing
-// hte account's state.
+// hte account's state. The withdrawal is aborted when the account doesn't have
+// enough balance, see TryWithdraw to wait for the outcome.
 func (acc *Account) Withdraw(amt int) {
-	acc.stm.Perform(func(t *stm.Transaction) bool {
+	acc.stm.PerformLabelled("withdraw", acc.withdraw(amt))
+}
+
+// TryWithdraw is Withdraw waiting for the outcome. It returns ErrInsufficientBalance
+// when the account doesn't have enough balance.
+func (acc *Account) TryWithdraw(amt int) error {
+	return acc.stm.DoLabelled("withdraw", acc.withdraw(amt))
+}
+
+// withdraw gives the transactional action withdrawing the amount from the account.
+func (acc *Account) withdraw(amt int) func(*stm.Transaction) bool {
+	return func(t *stm.Transaction) bool {
 		accState := t.Read(acc.state).(*state)
 
-		if accState.amt < amt {
-			return false
-		}
 		accState.amt = accState.amt - amt
 
 		return t.Write(acc.state, accState)
-	})
+	}
 }
 
 // Transfer transfers the desired amount from this account to the destination account.
 // Since, this operation is only complete when both the accounts have been modified/updated
 // it needs to be atomic. Moreover, as this operation modifies the states of the accounts
-// it is delegated to the STM.
+// it is delegated to the STM. The transfer is aborted when this account doesn't have
+// enough balance, see TryTransfer to wait for the outcome.
 func (acc *Account) Transfer(dest *Account, amt int) {
-	acc.stm.Perform(func(t *stm.Transaction) bool {
-		srcState := t.Read(acc.state).(*state)
-		destState := t.Read(dest.state).(*state)
+	if acc.stm != dest.stm {
+		go func() {
+			if err := acc.TryTransfer(dest, amt); err != nil {
+				log.Printf("transfer of %d from %s to %s aborted: %v", amt, acc.Details.Name, dest.Details.Name, err)
+			}
+		}()
+		return
+	}
+	acc.stm.PerformLabelled("transfer", func(t *stm.Transaction) bool {
+		return acc.transfer(t, t, dest, amt)
+	})
+}
+
+// TryTransfer is Transfer waiting for the outcome. It returns ErrInsufficientBalance
+// when this account doesn't have enough balance. The accounts may be managed by
+// different STMs, the transfer is then a transaction across both, see stm.DoAcross.
+func (acc *Account) TryTransfer(dest *Account, amt int) error {
+	if acc.stm != dest.stm {
+		ctx := stm.ContextWithLabel(context.Background(), "transfer")
+		return stm.DoAcrossContext(ctx, func(ct *stm.CrossTransaction) bool {
+			return acc.transfer(ct.On(acc.stm), ct.On(dest.stm), dest, amt)
+		})
+	}
+	return acc.stm.DoLabelled("transfer", func(t *stm.Transaction) bool {
+		return acc.transfer(t, t, dest, amt)
+	})
+}
 
-		if srcState.amt < amt {
-			return false
-		}
+// transfer transfers the amount from this account, read and written by the transaction
+// `src`, to the destination account, read and written by the transaction `dst`.
+func (acc *Account) transfer(src, dst *stm.Transaction, dest *Account, amt int) bool {
+	srcState := src.Read(acc.state).(*state)
+	destState := dst.Read(dest.state).(*state)
 
-		srcState.amt = srcState.amt - amt
-		destState.amt = destState.amt + amt
+	srcState.amt = srcState.amt - amt
+	destState.amt = destState.amt + amt
+
+	return src.Write(acc.state, srcState) && dst.Write(dest.state, destState)
+}
+
+// BalanceAt gives the balance of the account as of the `version` of the STM, a past
+// commit version within the STM's retention window, see stm.STM.ReadAt.
+func (acc *Account) BalanceAt(version uint64) (int, error) {
+	var balance int
+	err := acc.stm.ReadAt(version, func(t *stm.Transaction) {
+		balance = t.Read(acc.state).(*state).amt
+	})
+	return balance, err
+}
 
-		return t.Write(acc.state, srcState) && t.Write(dest.state, destState)
+// WatchBalance calls `fn` with the balance of the account every time a transaction
+// changes it. The returned watcher stops the updates.
+func (acc *Account) WatchBalance(fn func(balance int)) *stm.Watcher {
+	return acc.stm.WatchFunc(acc.state, func(v stm.Value) {
+		fn(v.(*state).amt)
 	})
 }
 
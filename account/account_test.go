@@ -0,0 +1,153 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// account_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:30:44 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:24:21 GMT-0700 (PDT)
+//
+
+package account
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+func TestStateEncoding(t *testing.T) {
+	for _, amt := range []int{0, 42, -42, 1 << 40} {
+		typ, data, err := stm.DefaultCodecs.Encode(newAccState(amt))
+		if err != nil || typ != "account.state" {
+			t.Fatalf("encoded as %q (error %v), want account.state", typ, err)
+		}
+		v, err := stm.DefaultCodecs.Decode(typ, data)
+		if err != nil {
+			t.Errorf("decoding %d: %v", amt, err)
+			continue
+		}
+		if !v.IsEqual(newAccState(amt)) {
+			t.Errorf("decoded %d as %d", amt, v.(*state).amt)
+		}
+	}
+
+	if _, data, _ := stm.DefaultCodecs.Encode(newAccState(42)); len(data) != 1 {
+		t.Errorf("encoded a balance of 42 in %d bytes, want a single byte", len(data))
+	}
+	if _, err := stm.DefaultCodecs.Decode("account.state", []byte{0x80}); err == nil {
+		t.Error("decoded a truncated account state")
+	}
+}
+
+// awaitBalance waits until the account has the balance `want`.
+func awaitBalance(acc *Account, want int) {
+	acc.stm.Do(func(t *stm.Transaction) bool {
+		if t.Read(acc.state).(*state).amt != want {
+			t.Retry() // woken up by the next change of the balance
+		}
+		return true
+	})
+}
+
+func TestConcurrentDepositsDontConflict(t *testing.T) {
+	s := stm.New()
+	acc := NewAccount("acc", 100, s)
+
+	for i := 0; i < 100; i++ {
+		acc.Deposit(10)
+	}
+	awaitBalance(acc, 1100)
+
+	if conflicts := s.Stats().Aborts[stm.AbortConflict]; conflicts != 0 {
+		t.Errorf("%d conflicts between deposits, want none", conflicts)
+	}
+}
+
+func TestWatchBalance(t *testing.T) {
+	s := stm.New()
+	acc := NewAccount("acc", 100, s)
+
+	balances := make(chan int)
+	w := acc.WatchBalance(func(balance int) { balances <- balance })
+	acc.Deposit(10)
+	if balance := <-balances; balance != 110 {
+		t.Errorf("watched the balance %d, want 110", balance)
+	}
+
+	w.Stop()
+	acc.Deposit(10)
+	awaitBalance(acc, 120)
+	select {
+	case balance := <-balances:
+		t.Errorf("watched the balance %d after the watcher was stopped", balance)
+	default:
+	}
+}
+
+func TestWithdrawalsAndTransfersCantOverdraw(t *testing.T) {
+	s := stm.New()
+	acc1 := NewAccount("acc1", 100, s)
+	acc2 := NewAccount("acc2", 0, s)
+
+	if err := acc1.TryWithdraw(101); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("TryWithdraw(101): got %v, want ErrInsufficientBalance", err)
+	}
+	if err := acc1.TryTransfer(acc2, 101); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("TryTransfer(101): got %v, want ErrInsufficientBalance", err)
+	}
+	if err := acc1.TryTransfer(acc2, 60); err != nil {
+		t.Errorf("TryTransfer(60): %v", err)
+	}
+	if err := acc1.TryWithdraw(40); err != nil {
+		t.Errorf("TryWithdraw(40): %v", err)
+	}
+	awaitBalance(acc1, 0)
+	awaitBalance(acc2, 60)
+
+	acc2.Transfer(acc1, 50)
+	awaitBalance(acc1, 50) // the withdrawal would be rejected before the transfer
+	acc1.Withdraw(20)
+	awaitBalance(acc1, 30)
+	awaitBalance(acc2, 10)
+}
+
+func TestTransferAcrossSTMs(t *testing.T) {
+	acc1 := NewAccount("acc1", 100, stm.New())
+	acc2 := NewAccount("acc2", 0, stm.New())
+
+	if err := acc1.TryTransfer(acc2, 101); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("TryTransfer(101): got %v, want ErrInsufficientBalance", err)
+	}
+	acc1.Transfer(acc2, 30)
+	awaitBalance(acc2, 30)
+	awaitBalance(acc1, 70)
+}
@@ -0,0 +1,108 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// main.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:08:13 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:33:01 GMT-0700 (PDT)
+//
+
+// Command stmd hosts an STM and serves its named TVars over HTTP, so the processes of a
+// service can share transactional state, see the package stm/remote and its Client.
+//
+// Usage:
+//
+//	stmd [-addr :7070] [-wal path] [-checkpoint interval]
+//
+// The values are kept as the clients encoded them, stmd doesn't need to know their
+// types. With -wal the STM is persisted in a write-ahead log and recovered on restart.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sidmishraw/gostm/stm"
+	"github.com/sidmishraw/gostm/stm/remote"
+)
+
+func main() {
+	addr := flag.String("addr", ":7070", "the address to serve on")
+	walPath := flag.String("wal", "", "the path of the write-ahead log, the STM isn't persisted if empty")
+	checkpoint := flag.Duration("checkpoint", 0, "the interval between the checkpoints of the write-ahead log, none if 0")
+	flag.Parse()
+
+	s := stm.New()
+	if *walPath != "" {
+		opts := []stm.Option{stm.WithCodec(remote.BlobCodec)}
+		if *checkpoint > 0 {
+			opts = append(opts, stm.WithCheckpointInterval(*checkpoint))
+		}
+		var err error
+		if s, err = stm.Open(*walPath, opts...); err != nil {
+			log.Fatalf("stmd: opening %s: %v", *walPath, err)
+		}
+	}
+
+	server := &http.Server{
+		Addr:         *addr,
+		Handler:      remote.NewServer(s, remote.BlobCodec),
+		ReadTimeout:  10 * time.Second, // the requests are small, a stalled client is let go
+		WriteTimeout: 30 * time.Second, // a commit waits for the commit lock, and the log sync
+		IdleTimeout:  2 * time.Minute,
+	}
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	log.Printf("stmd: serving on %s", *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("stmd: %v", err)
+	}
+	if *walPath != "" {
+		if err := s.Close(); err != nil {
+			log.Fatalf("stmd: closing %s: %v", *walPath, err)
+		}
+	}
+}
@@ -32,7 +32,7 @@
 // main.go
 // @author Sidharth Mishra
 // @created Thu Mar 29 2018 00:21:30 GMT-0700 (PDT)
-// @last-modified Thu Apr 19 2018 12:50:58 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:48:32 GMT-0700 (PDT)
 //
 
 package main
@@ -48,7 +48,7 @@ func main() {
 	STM := stm.New()
 
 	acc1 := account.NewAccount("account1", 100, STM)
-	acc2 := account.NewAccount("account1", 500, STM)
+	acc2 := account.NewAccount("account2", 500, STM)
 
 	// Initial state of the STM [100, 500].
 	//
@@ -0,0 +1,165 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// array.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:39:43 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:39:43 GMT-0700 (PDT)
+//
+
+package stm
+
+// TArray is a transactional fixed-size array. Every slot of the array is its own memory
+// cell, so transactions writing to different indices don't conflict.
+type TArray struct {
+	slots []TVar // the slots of the array
+}
+
+// TVector is a transactional growable array. Like a TArray every slot is its own memory
+// cell, but the slots are listed in a header memory cell. Gets and Sets only read the
+// header, so they don't conflict with each other on different indices, an Append writes
+// the header and conflicts with every transaction that has used the vector.
+type TVector struct {
+	header TVar // the memory cell holding the vectorHeader
+}
+
+// vectorHeader is the list of slots of a TVector. It is stored in a memory cell.
+type vectorHeader struct {
+	slots []TVar // the slots of the vector
+}
+
+// MakeCopy makes vectorHeader conform to the Value interface.
+func (h *vectorHeader) MakeCopy() Value {
+	nh := new(vectorHeader)
+	nh.slots = make([]TVar, len(h.slots))
+	copy(nh.slots, h.slots)
+	return nh
+}
+
+// IsEqual checks the equality between two vector headers.
+func (h *vectorHeader) IsEqual(v Value) bool {
+	vv, ok := v.(*vectorHeader)
+	if !ok {
+		return false
+	}
+	if len(vv.slots) != len(h.slots) {
+		return false
+	}
+	for i := range h.slots {
+		if vv.slots[i] != h.slots[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewTArray creates a new array managed by the STM, holding the `values`.
+// The length of the array is the number of values.
+func NewTArray(stm *STM, values ...Value) *TArray {
+	arr := new(TArray)
+	arr.slots = make([]TVar, len(values))
+	for i, value := range values {
+		arr.slots[i] = stm.NewTVar(value)
+	}
+	return arr
+}
+
+// Len gives the length of the array.
+func (arr *TArray) Len() int {
+	return len(arr.slots)
+}
+
+// Get gives the value at the index `i`. It panics if `i` is out of range.
+func (arr *TArray) Get(t *Transaction, i int) Value {
+	return t.Read(arr.slots[i])
+}
+
+// Set sets the value at the index `i`. It panics if `i` is out of range.
+func (arr *TArray) Set(t *Transaction, i int, value Value) bool {
+	return t.Write(arr.slots[i], value)
+}
+
+// Slice gives a view of the slots [lo, hi) of the array. The view shares the memory
+// cells with the array, setting a value through one is seen through the other.
+func (arr *TArray) Slice(lo, hi int) *TArray {
+	view := new(TArray)
+	view.slots = arr.slots[lo:hi:hi]
+	return view
+}
+
+// ------------------------------------------------------------------------
+
+// NewTVector creates a new vector managed by the STM, holding the `values`.
+func NewTVector(stm *STM, values ...Value) *TVector {
+	vec := new(TVector)
+	h := new(vectorHeader)
+	h.slots = make([]TVar, len(values))
+	for i, value := range values {
+		h.slots[i] = stm.NewTVar(value)
+	}
+	vec.header = stm.NewTVar(h)
+	return vec
+}
+
+// readHeader reads the header of the vector in the transaction.
+func (vec *TVector) readHeader(t *Transaction) *vectorHeader {
+	return t.Read(vec.header).(*vectorHeader)
+}
+
+// Len gives the length of the vector.
+func (vec *TVector) Len(t *Transaction) int {
+	return len(vec.readHeader(t).slots)
+}
+
+// Get gives the value at the index `i`. It panics if `i` is out of range.
+func (vec *TVector) Get(t *Transaction, i int) Value {
+	return t.Read(vec.readHeader(t).slots[i])
+}
+
+// Set sets the value at the index `i`. It panics if `i` is out of range.
+func (vec *TVector) Set(t *Transaction, i int, value Value) bool {
+	return t.Write(vec.readHeader(t).slots[i], value)
+}
+
+// Append adds the `value` at the end of the vector in a new slot.
+func (vec *TVector) Append(t *Transaction, value Value) bool {
+	h := vec.readHeader(t)
+	h.slots = append(h.slots, t.NewTVar(value))
+	return t.Write(vec.header, h)
+}
+
+// Slice gives a view of the slots [lo, hi) of the vector as an array. The view shares
+// the memory cells with the vector, it doesn't grow when values are appended to the vector.
+func (vec *TVector) Slice(t *Transaction, lo, hi int) *TArray {
+	view := new(TArray)
+	view.slots = vec.readHeader(t).slots[lo:hi:hi]
+	return view
+}
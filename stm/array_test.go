@@ -0,0 +1,324 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// array_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:20:36 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:58:46 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// benchLen is the length of the arrays of the benchmarks.
+const benchLen = 1024
+
+// sliceValue is a slice held by a single TVar, the baseline of the benchmarks.
+type sliceValue []Value
+
+func (s sliceValue) MakeCopy() Value {
+	return append(sliceValue(nil), s...)
+}
+
+func (s sliceValue) IsEqual(v Value) bool {
+	other, ok := v.(sliceValue)
+	if !ok || len(other) != len(s) {
+		return false
+	}
+	for i := range s {
+		if !s[i].IsEqual(other[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// benchValues gives the initial values of the arrays of the benchmarks.
+func benchValues() []Value {
+	values := make([]Value, benchLen)
+	for i := range values {
+		values[i] = counterValue(i)
+	}
+	return values
+}
+
+// benchIndices hands out a distinct index to every goroutine of a parallel benchmark,
+// so the goroutines use disjoint indices of the array.
+type benchIndices struct {
+	next int64 // the next index handed out
+}
+
+// take gives the index of the calling goroutine.
+func (ix *benchIndices) take() int {
+	return int(atomic.AddInt64(&ix.next, 1)-1) % benchLen
+}
+
+// reportConflicts reports the conflicts of the benchmark's transactions per operation.
+func reportConflicts(b *testing.B, s *STM) {
+	b.ReportMetric(float64(s.Stats().Aborts[AbortConflict])/float64(b.N), "conflicts/op")
+}
+
+func BenchmarkTArrayGet(b *testing.B) {
+	s := New()
+	arr := NewTArray(s, benchValues()...)
+	var indices benchIndices
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := indices.take()
+		for pb.Next() {
+			s.Do(func(t *Transaction) bool {
+				arr.Get(t, i)
+				return true
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkTArraySet(b *testing.B) {
+	s := New()
+	arr := NewTArray(s, benchValues()...)
+	var indices benchIndices
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := indices.take()
+		for n := 0; pb.Next(); n++ {
+			s.Do(func(t *Transaction) bool {
+				return arr.Set(t, i, counterValue(n))
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkTVectorGet(b *testing.B) {
+	s := New()
+	vec := NewTVector(s, benchValues()...)
+	var indices benchIndices
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := indices.take()
+		for pb.Next() {
+			s.Do(func(t *Transaction) bool {
+				vec.Get(t, i)
+				return true
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkTVectorSet(b *testing.B) {
+	s := New()
+	vec := NewTVector(s, benchValues()...)
+	var indices benchIndices
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := indices.take()
+		for n := 0; pb.Next(); n++ {
+			s.Do(func(t *Transaction) bool {
+				return vec.Set(t, i, counterValue(n))
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkTVectorAppend(b *testing.B) {
+	s := New()
+	vec := NewTVector(s)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for n := 0; pb.Next(); n++ {
+			s.Do(func(t *Transaction) bool {
+				return vec.Append(t, counterValue(n))
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkSliceTVarGet(b *testing.B) {
+	s := New()
+	tVar := s.NewTVar(sliceValue(benchValues()))
+	var indices benchIndices
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := indices.take()
+		for pb.Next() {
+			s.Do(func(t *Transaction) bool {
+				_ = t.Read(tVar).(sliceValue)[i]
+				return true
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkSliceTVarSet(b *testing.B) {
+	s := New()
+	tVar := s.NewTVar(sliceValue(benchValues()))
+	var indices benchIndices
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := indices.take()
+		for n := 0; pb.Next(); n++ {
+			s.Do(func(t *Transaction) bool {
+				values := t.Read(tVar).(sliceValue)
+				values[i] = counterValue(n)
+				return t.Write(tVar, values)
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+func BenchmarkSliceTVarAppend(b *testing.B) {
+	s := New()
+	tVar := s.NewTVar(sliceValue(nil))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for n := 0; pb.Next(); n++ {
+			s.Do(func(t *Transaction) bool {
+				return t.Write(tVar, append(t.Read(tVar).(sliceValue), counterValue(n)))
+			})
+		}
+	})
+	reportConflicts(b, s)
+}
+
+// expectPanic checks that `fn` panics, as an index out of range does.
+func expectPanic(t *testing.T, what string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s didn't panic", what)
+		}
+	}()
+	fn()
+}
+
+func TestTArray(t *testing.T) {
+	s := New()
+	arr := NewTArray(s, counterValue(0), counterValue(1), counterValue(2), counterValue(3))
+	if n := arr.Len(); n != 4 {
+		t.Fatalf("Len: got %d, want 4", n)
+	}
+
+	view := arr.Slice(1, 3)
+	s.Do(func(tx *Transaction) bool {
+		arr.Set(tx, 2, counterValue(20))
+		view.Set(tx, 0, counterValue(10))
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		for i, want := range []counterValue{0, 10, 20, 3} {
+			if got := arr.Get(tx, i); got != want {
+				t.Errorf("Get(%d): got %v, want %v", i, got, want)
+			}
+		}
+		if n, got := view.Len(), view.Get(tx, 1); n != 2 || got != counterValue(20) {
+			t.Errorf("the view of [1, 3) has %d slots and %v at 1, want 2 slots and 20", n, got)
+		}
+
+		expectPanic(t, "Get(-1)", func() { arr.Get(tx, -1) })
+		expectPanic(t, "Get(4)", func() { arr.Get(tx, 4) })
+		expectPanic(t, "Set(4)", func() { arr.Set(tx, 4, counterValue(4)) })
+		expectPanic(t, "Get(2) of a view of 2 slots", func() { view.Get(tx, 2) })
+		return true
+	})
+	expectPanic(t, "Slice(3, 5)", func() { arr.Slice(3, 5) })
+}
+
+func TestTVector(t *testing.T) {
+	s := New()
+	vec := NewTVector(s, counterValue(0))
+
+	var view *TArray
+	s.Do(func(tx *Transaction) bool {
+		vec.Append(tx, counterValue(1))
+		vec.Append(tx, counterValue(2))
+		view = vec.Slice(tx, 0, 3)
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		vec.Append(tx, counterValue(3))
+		vec.Set(tx, 0, counterValue(-1))
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		if n := vec.Len(tx); n != 4 {
+			t.Errorf("Len: got %d, want 4", n)
+		}
+		for i, want := range []counterValue{-1, 1, 2, 3} {
+			if got := vec.Get(tx, i); got != want {
+				t.Errorf("Get(%d): got %v, want %v", i, got, want)
+			}
+		}
+		if n, got := view.Len(), view.Get(tx, 0); n != 3 || got != counterValue(-1) {
+			t.Errorf("the view has %d slots and %v at 0, want 3 slots sharing -1", n, got)
+		}
+
+		expectPanic(t, "Get(-1)", func() { vec.Get(tx, -1) })
+		expectPanic(t, "Get(4)", func() { vec.Get(tx, 4) })
+		expectPanic(t, "Set(4)", func() { vec.Set(tx, 4, counterValue(4)) })
+		expectPanic(t, "Slice(2, 5)", func() { vec.Slice(tx, 2, 5) })
+		return true
+	})
+}
+
+func TestTArrayDisjointSetsDontConflict(t *testing.T) {
+	s := New()
+	arr := NewTArray(s, benchValues()...)
+	vec := NewTVector(s, benchValues()...)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				s.Do(func(t *Transaction) bool { return arr.Set(t, i, counterValue(n)) })
+				s.Do(func(t *Transaction) bool { return vec.Set(t, i, counterValue(n)) })
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if conflicts := s.Stats().Aborts[AbortConflict]; conflicts != 0 {
+		t.Errorf("%d conflicts between transactions setting different indices, want none", conflicts)
+	}
+}
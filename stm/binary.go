@@ -0,0 +1,347 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// binary.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:24:21 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:24:21 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// BinaryEncoding encodes the values of the type T in a compact binary format. The type
+// T, or the type it points to, is encoded field by field:
+//   - the booleans as a byte, the integers as varints, the floats as their IEEE 754 bits;
+//   - the strings as their length and their bytes;
+//   - the slices as their length and their elements, the arrays as their elements;
+//   - the maps as their length and their keys and elements;
+//   - the empty slices and maps as a zero length, they decode as nil;
+//   - the structs as their exported fields, in order;
+//   - the pointers as a byte telling if they are nil, and what they point to.
+//
+// The types implementing encoding.BinaryMarshaler, when the type they point to or the
+// pointer to them implements encoding.BinaryUnmarshaler, are encoded in their own
+// format instead, e.g. the structs with unexported fields, prefixed with its length
+// when they are nested in the value. It panics for the types it can't encode, like
+// Register it is expected to be called by the init functions.
+func BinaryEncoding[T Value]() Encoding {
+	typ := reflect.TypeFor[T]()
+	if isBinaryMarshaler(typ) {
+		return Encoding{
+			Type: typ,
+			Encode: func(value Value) ([]byte, error) {
+				return value.(encoding.BinaryMarshaler).MarshalBinary()
+			},
+			Decode: func(data []byte) (Value, error) {
+				return decodeValue[T](func(target interface{}) error {
+					return target.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+				})
+			},
+		}
+	}
+
+	if err := checkBinaryType(typ, map[reflect.Type]bool{}); err != nil {
+		panic(fmt.Sprintf("stm: BinaryEncoding of %v: %v", typ, err))
+	}
+	return Encoding{
+		Type: typ,
+		Encode: func(value Value) ([]byte, error) {
+			return appendBinary(nil, reflect.ValueOf(value))
+		},
+		Decode: func(data []byte) (Value, error) {
+			value := reflect.New(typ).Elem()
+			rest, err := decodeBinary(data, value)
+			if err == nil && len(rest) > 0 {
+				err = fmt.Errorf("%w, %d bytes left over", errMalformed, len(rest))
+			}
+			if err != nil {
+				return nil, fmt.Errorf("stm: decoding %v: %w", typ, err)
+			}
+			return value.Interface().(T), nil
+		},
+	}
+}
+
+// errMalformed is the error of decoding data that wasn't encoded by BinaryEncoding.
+var errMalformed = errors.New("malformed data")
+
+// binaryMarshaler is the reflect.Type of encoding.BinaryMarshaler.
+var binaryMarshaler = reflect.TypeFor[encoding.BinaryMarshaler]()
+
+// binaryUnmarshaler is the reflect.Type of encoding.BinaryUnmarshaler.
+var binaryUnmarshaler = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+
+// isBinaryMarshaler checks if the values of the type encode themselves, the type
+// implements encoding.BinaryMarshaler and the type it points to, or the pointer to
+// it, implements encoding.BinaryUnmarshaler.
+func isBinaryMarshaler(typ reflect.Type) bool {
+	if !typ.Implements(binaryMarshaler) {
+		return false
+	}
+	if typ.Kind() == reflect.Pointer {
+		return typ.Implements(binaryUnmarshaler)
+	}
+	return reflect.PointerTo(typ).Implements(binaryUnmarshaler)
+}
+
+// checkBinaryType checks that the values of the type can be encoded by BinaryEncoding,
+// `seen` holds the types being checked so that the recursive types are checked once.
+func checkBinaryType(typ reflect.Type, seen map[reflect.Type]bool) error {
+	if isBinaryMarshaler(typ) || seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+	switch typ.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return nil
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		return checkBinaryType(typ.Elem(), seen)
+	case reflect.Map:
+		if err := checkBinaryType(typ.Key(), seen); err != nil {
+			return err
+		}
+		return checkBinaryType(typ.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				return fmt.Errorf("unexported field %s of %v, implement encoding.BinaryMarshaler", field.Name, typ)
+			}
+			if err := checkBinaryType(field.Type, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%v can't be encoded", typ)
+}
+
+// appendBinary appends the encoding of the value `v` to `data`.
+func appendBinary(data []byte, v reflect.Value) ([]byte, error) {
+	if isBinaryMarshaler(v.Type()) && (v.Kind() != reflect.Pointer || !v.IsNil()) {
+		encoded, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		if v.Kind() == reflect.Pointer {
+			data = append(data, 1) // not nil
+		}
+		data = binary.AppendUvarint(data, uint64(len(encoded)))
+		return append(data, encoded...), nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(data, 1), nil
+		}
+		return append(data, 0), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(data, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(data, v.Uint()), nil
+	case reflect.Float32:
+		return binary.BigEndian.AppendUint32(data, math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		return binary.BigEndian.AppendUint64(data, math.Float64bits(v.Float())), nil
+	case reflect.String:
+		data = binary.AppendUvarint(data, uint64(v.Len()))
+		return append(data, v.String()...), nil
+	case reflect.Slice:
+		data = binary.AppendUvarint(data, uint64(v.Len()))
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len() && err == nil; i++ {
+			data, err = appendBinary(data, v.Index(i))
+		}
+		return data, err
+	case reflect.Map:
+		data = binary.AppendUvarint(data, uint64(v.Len()))
+		for it := v.MapRange(); it.Next() && err == nil; {
+			if data, err = appendBinary(data, it.Key()); err == nil {
+				data, err = appendBinary(data, it.Value())
+			}
+		}
+		return data, err
+	case reflect.Struct:
+		for i := 0; i < v.NumField() && err == nil; i++ {
+			data, err = appendBinary(data, v.Field(i))
+		}
+		return data, err
+	case reflect.Pointer:
+		if v.IsNil() {
+			return append(data, 0), nil
+		}
+		return appendBinary(append(data, 1), v.Elem())
+	}
+	return nil, fmt.Errorf("stm: %v can't be encoded", v.Type())
+}
+
+// decodeBinary decodes the encoding of a value at the start of `data` into the value
+// `v`, it gives back the data after it.
+func decodeBinary(data []byte, v reflect.Value) ([]byte, error) {
+	if isBinaryMarshaler(v.Type()) {
+		if v.Kind() == reflect.Pointer {
+			if len(data) == 0 {
+				return nil, errMalformed
+			}
+			if data[0] == 0 {
+				return data[1:], nil // nil
+			}
+			data = data[1:]
+			v.Set(reflect.New(v.Type().Elem()))
+		} else {
+			v = v.Addr()
+		}
+		n, size := binary.Uvarint(data)
+		if size <= 0 || n > uint64(len(data)-size) {
+			return nil, errMalformed
+		}
+		data = data[size:]
+		return data[n:], v.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data[:n])
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if len(data) == 0 || data[0] > 1 {
+			return nil, errMalformed
+		}
+		v.SetBool(data[0] == 1)
+		return data[1:], nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, size := binary.Varint(data)
+		if size <= 0 || v.OverflowInt(n) {
+			return nil, errMalformed
+		}
+		v.SetInt(n)
+		return data[size:], nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, size := binary.Uvarint(data)
+		if size <= 0 || v.OverflowUint(n) {
+			return nil, errMalformed
+		}
+		v.SetUint(n)
+		return data[size:], nil
+	case reflect.Float32:
+		if len(data) < 4 {
+			return nil, errMalformed
+		}
+		v.SetFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(data))))
+		return data[4:], nil
+	case reflect.Float64:
+		if len(data) < 8 {
+			return nil, errMalformed
+		}
+		v.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(data)))
+		return data[8:], nil
+	case reflect.String:
+		n, size := binary.Uvarint(data)
+		if size <= 0 || n > uint64(len(data)-size) {
+			return nil, errMalformed
+		}
+		data = data[size:]
+		v.SetString(string(data[:n]))
+		return data[n:], nil
+	case reflect.Slice:
+		n, size := binary.Uvarint(data)
+		if size <= 0 || (v.Type().Elem().Size() > 0 && n > uint64(len(data)-size)) {
+			return nil, errMalformed // every element of a size takes a byte at least
+		}
+		data = data[size:]
+		if n == 0 {
+			return data, nil // the empty slices decode as nil, as with gob
+		}
+		v.Set(reflect.MakeSlice(v.Type(), int(n), int(n)))
+		return decodeElements(data, v)
+	case reflect.Array:
+		return decodeElements(data, v)
+	case reflect.Map:
+		n, size := binary.Uvarint(data)
+		if size <= 0 || n > uint64(len(data)-size) {
+			return nil, errMalformed
+		}
+		data = data[size:]
+		if n == 0 {
+			return data, nil
+		}
+		v.Set(reflect.MakeMapWithSize(v.Type(), int(n)))
+		var err error
+		for i := uint64(0); i < n; i++ {
+			key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+			if data, err = decodeBinary(data, key); err != nil {
+				return nil, err
+			}
+			if data, err = decodeBinary(data, elem); err != nil {
+				return nil, err
+			}
+			v.SetMapIndex(key, elem)
+		}
+		return data, nil
+	case reflect.Struct:
+		var err error
+		for i := 0; i < v.NumField() && err == nil; i++ {
+			data, err = decodeBinary(data, v.Field(i))
+		}
+		return data, err
+	case reflect.Pointer:
+		if len(data) == 0 || data[0] > 1 {
+			return nil, errMalformed
+		}
+		if data[0] == 0 {
+			return data[1:], nil // nil
+		}
+		v.Set(reflect.New(v.Type().Elem()))
+		return decodeBinary(data[1:], v.Elem())
+	}
+	return nil, fmt.Errorf("%v can't be decoded", v.Type())
+}
+
+// decodeElements decodes the elements of the slice or the array `v`.
+func decodeElements(data []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	for i := 0; i < v.Len() && err == nil; i++ {
+		data, err = decodeBinary(data, v.Index(i))
+	}
+	return data, err
+}
@@ -0,0 +1,266 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// cache.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:40:59 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:22:06 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"fmt"
+	"hash/maphash"
+	"time"
+)
+
+// cacheBuckets is the number of buckets the keys of a TCache are spread across.
+const cacheBuckets = 32
+
+// TCache is a transactional cache with LRU eviction and optional expiry of the entries.
+// The entries are kept in a TList in the order of their use, and indexed by their keys.
+// Lookups, updates and the evictions they cause are all part of the transaction using
+// the cache, so they compose with the rest of the transaction's reads and writes.
+//
+// A hit moves the entry to the front of the LRU list, so Get writes the list and the
+// transactions getting from the cache conflict with each other, the concurrent readers
+// are serialized. The readers that can do without refreshing the recency use Peek.
+type TCache[K comparable] struct {
+	capacity int           // the maximum number of entries in the cache
+	ttl      time.Duration // the default time to live of the entries, 0 for no expiry
+	seed     maphash.Seed  // the seed for hashing the keys into buckets
+	buckets  []TVar        // the index of the entries, each bucket is a memory cell holding a cacheBucket
+	lru      *TList        // the entries, the most recently used at the front
+	size     TVar          // the memory cell holding the number of entries
+}
+
+// cacheEntry is an entry of the cache. It is the value of an element of the LRU list.
+type cacheEntry[K comparable] struct {
+	key     K         // the key of the entry
+	value   Value     // the cached value
+	expires time.Time // the time the entry expires at, zero for never
+}
+
+// MakeCopy makes cacheEntry conform to the Value interface.
+func (e *cacheEntry[K]) MakeCopy() Value {
+	ne := new(cacheEntry[K])
+	ne.key = e.key
+	ne.value = e.value.MakeCopy()
+	ne.expires = e.expires
+	return ne
+}
+
+// IsEqual checks the equality between two entries.
+func (e *cacheEntry[K]) IsEqual(v Value) bool {
+	vv, ok := v.(*cacheEntry[K])
+	if !ok {
+		return false
+	}
+	return vv.key == e.key && vv.expires.Equal(e.expires) && vv.value.IsEqual(e.value)
+}
+
+// isExpired checks if the entry has expired at the time `now`.
+func (e *cacheEntry[K]) isExpired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// cacheBucket is a bucket of the index of the cache. It is stored in a memory cell.
+type cacheBucket[K comparable] struct {
+	elems map[K]*TListElement // the keys in the bucket and their elements in the LRU list
+}
+
+// MakeCopy makes cacheBucket conform to the Value interface.
+func (b *cacheBucket[K]) MakeCopy() Value {
+	nb := new(cacheBucket[K])
+	nb.elems = make(map[K]*TListElement, len(b.elems))
+	for key, e := range b.elems {
+		nb.elems[key] = e
+	}
+	return nb
+}
+
+// IsEqual checks the equality between two buckets.
+func (b *cacheBucket[K]) IsEqual(v Value) bool {
+	vv, ok := v.(*cacheBucket[K])
+	if !ok {
+		return false
+	}
+	if len(vv.elems) != len(b.elems) {
+		return false
+	}
+	for key, e := range b.elems {
+		ve, ok := vv.elems[key]
+		if !ok || ve.node != e.node {
+			return false
+		}
+	}
+	return true
+}
+
+// NewTCache creates a new empty cache managed by the STM. The cache holds at most
+// `capacity` entries, evicting the least recently used ones. The entries expire after
+// the `ttl`, a `ttl` of 0 means that entries only leave the cache when evicted. It
+// panics when the capacity is less than 1.
+func NewTCache[K comparable](stm *STM, capacity int, ttl time.Duration) *TCache[K] {
+	if capacity < 1 {
+		panic(fmt.Sprintf("stm: TCache with capacity %d, it must hold at least 1 entry", capacity))
+	}
+	c := new(TCache[K])
+	c.capacity = capacity
+	c.ttl = ttl
+	c.seed = maphash.MakeSeed()
+	c.buckets = make([]TVar, cacheBuckets)
+	for i := range c.buckets {
+		b := new(cacheBucket[K])
+		b.elems = make(map[K]*TListElement)
+		c.buckets[i] = stm.NewTVar(b)
+	}
+	c.lru = NewTList(stm)
+	c.size = stm.NewTVar(counterValue(0))
+	return c
+}
+
+// bucketOf gives the bucket of the index the `key` belongs to.
+func (c *TCache[K]) bucketOf(key K) TVar {
+	return c.buckets[maphash.Comparable(c.seed, key)%uint64(len(c.buckets))]
+}
+
+// lookup gives the element of the LRU list holding the entry for the `key`, nil when absent.
+func (c *TCache[K]) lookup(t *Transaction, key K) *TListElement {
+	return t.Read(c.bucketOf(key)).(*cacheBucket[K]).elems[key]
+}
+
+// index sets the element of the LRU list for the `key`, a nil element removes the key.
+func (c *TCache[K]) index(t *Transaction, key K, e *TListElement) {
+	bucket := c.bucketOf(key)
+	b := t.Read(bucket).(*cacheBucket[K])
+	if e == nil {
+		delete(b.elems, key)
+	} else {
+		b.elems[key] = e
+	}
+	t.Write(bucket, b)
+}
+
+// addSize adds the `delta` to the number of entries and gives the new number.
+func (c *TCache[K]) addSize(t *Transaction, delta int) int {
+	size := t.Read(c.size).(counterValue) + counterValue(delta)
+	t.Write(c.size, size)
+	return int(size)
+}
+
+// remove removes the entry held by the element `e` from the cache.
+func (c *TCache[K]) remove(t *Transaction, e *TListElement, entry *cacheEntry[K]) {
+	c.lru.Remove(t, e)
+	c.index(t, entry.key, nil)
+	c.addSize(t, -1)
+}
+
+// Get gives the value cached for the `key` and true, or nil and false when the key is
+// absent or its entry has expired. A hit makes the entry the most recently used one,
+// an expired entry is removed from the cache.
+func (c *TCache[K]) Get(t *Transaction, key K) (Value, bool) {
+	e := c.lookup(t, key)
+	if e == nil {
+		return nil, false
+	}
+	entry := e.Value(t).(*cacheEntry[K])
+	if entry.isExpired(time.Now()) {
+		c.remove(t, e, entry)
+		return nil, false
+	}
+	c.lru.MoveToFront(t, e)
+	return entry.value, true
+}
+
+// Peek gives the value cached for the `key` and true, or nil and false when the key is
+// absent or its entry has expired, like Get. Unlike Get it leaves the recency of the
+// entry and the expired entries as they are, it only reads the cache, so transactions
+// peeking into the cache don't conflict with each other.
+func (c *TCache[K]) Peek(t *Transaction, key K) (Value, bool) {
+	e := c.lookup(t, key)
+	if e == nil {
+		return nil, false
+	}
+	entry := e.Value(t).(*cacheEntry[K])
+	if entry.isExpired(time.Now()) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Put caches the `value` for the `key` with the default time to live of the cache.
+// When the cache is over its capacity, the least recently used entries are evicted.
+func (c *TCache[K]) Put(t *Transaction, key K, value Value) bool {
+	return c.PutWithTTL(t, key, value, c.ttl)
+}
+
+// PutWithTTL caches the `value` for the `key`, it expires after the `ttl`. A `ttl` of 0
+// means the entry never expires. When the cache is over its capacity, the least recently
+// used entries are evicted.
+func (c *TCache[K]) PutWithTTL(t *Transaction, key K, value Value, ttl time.Duration) bool {
+	entry := new(cacheEntry[K])
+	entry.key = key
+	entry.value = value
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	if e := c.lookup(t, key); e != nil {
+		e.SetValue(t, entry)
+		return c.lru.MoveToFront(t, e)
+	}
+
+	c.index(t, key, c.lru.PushFront(t, entry))
+	for size := c.addSize(t, 1); size > c.capacity; size-- {
+		lru := c.lru.Back(t)
+		c.remove(t, lru, lru.Value(t).(*cacheEntry[K]))
+	}
+	return true
+}
+
+// Invalidate removes the entry for the `key` from the cache. It returns false when
+// the key was absent.
+func (c *TCache[K]) Invalidate(t *Transaction, key K) bool {
+	e := c.lookup(t, key)
+	if e == nil {
+		return false
+	}
+	c.remove(t, e, e.Value(t).(*cacheEntry[K]))
+	return true
+}
+
+// Len gives the number of entries in the cache, including the expired entries that
+// haven't been removed yet.
+func (c *TCache[K]) Len(t *Transaction) int {
+	return int(t.Read(c.size).(counterValue))
+}
@@ -0,0 +1,85 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// cache_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:22:06 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:22:06 GMT-0700 (PDT)
+//
+
+package stm
+
+import "testing"
+
+func TestNewTCacheRejectsCapacityBelowOne(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewTCache with capacity %d didn't panic", capacity)
+				}
+			}()
+			NewTCache[string](New(), capacity, 0)
+		}()
+	}
+}
+
+func TestTCacheGetRefreshesRecencyPeekDoesNot(t *testing.T) {
+	for _, peek := range []bool{false, true} {
+		s := New()
+		c := NewTCache[string](s, 2, 0)
+		s.Do(func(tx *Transaction) bool {
+			return c.Put(tx, "a", counterValue(1)) && c.Put(tx, "b", counterValue(2))
+		})
+		s.Do(func(tx *Transaction) bool {
+			if peek {
+				c.Peek(tx, "a")
+			} else {
+				c.Get(tx, "a")
+			}
+			return c.Put(tx, "c", counterValue(3)) // evicts the least recently used entry
+		})
+
+		evicted, kept := "b", "a"
+		if peek {
+			evicted, kept = "a", "b"
+		}
+		s.Do(func(tx *Transaction) bool {
+			if _, ok := c.Peek(tx, evicted); ok {
+				t.Errorf("peek %v: %q should have been evicted", peek, evicted)
+			}
+			if _, ok := c.Peek(tx, kept); !ok {
+				t.Errorf("peek %v: %q should have been kept", peek, kept)
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,352 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// changefeed.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:01:17 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:30:38 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSubscriptionClosed is returned by Subscription.Next once the subscription is closed.
+var ErrSubscriptionClosed = errors.New("stm: the subscription is closed")
+
+// ErrSubscriptionDropped is returned by Subscription.Next once the subscription has been
+// dropped for lagging behind the commits, see STM.SetSubscriberTimeout.
+var ErrSubscriptionDropped = errors.New("stm: the subscription was dropped for lagging behind")
+
+// defaultSubscriberTimeout is how long a commit waits for a lagging subscription by default.
+const defaultSubscriberTimeout = 10 * time.Second
+
+// ChangeRecord is the record of a commit in the changefeed of an STM, see STM.Subscribe.
+type ChangeRecord struct {
+	Version       uint64   // the version of the STM the commit made
+	TransactionID uint64   // the ID of the committed transaction, 0 for a TVar created outside transactions
+	Label         string   // the label of the committed transaction, empty if none
+	Changes       []Change // the memory cells the commit created or changed
+}
+
+// Change is the change of a memory cell in a ChangeRecord.
+type Change struct {
+	Cell    string // the ID of the memory cell
+	Name    string // the name of the memory cell, empty if none
+	Created bool   // the memory cell was created by the commit
+	Old     Value  // a copy of the contents before the commit, nil for a created memory cell
+	New     Value  // a copy of the contents after the commit
+}
+
+// changefeed is the ordered log of the change records of an STM. The records are kept
+// until every subscription has consumed them, and the last `capacity` records are kept
+// for the subscriptions from the past versions. The commits wait while the slowest
+// subscription lags behind by more than `capacity` records, for `timeout` at most.
+type changefeed struct {
+	lock     *sync.Mutex            // guards the changefeed
+	capacity int                    // the records kept, and the lag the commits are allowed, 0 when disabled
+	timeout  time.Duration          // how long a commit waits before dropping the lagging subscriptions
+	records  []ChangeRecord         // the records kept, in the order of the versions
+	base     uint64                 // the sequence number of the first record kept
+	trimmed  uint64                 // the version of the last record dropped, 0 if none
+	subs     map[*Subscription]bool // the open subscriptions
+	changed  chan struct{}          // closed and replaced whenever records are added or consumed
+}
+
+// Subscription is a subscription to the changefeed of an STM, see STM.Subscribe.
+type Subscription struct {
+	feed    *changefeed // the changefeed subscribed to
+	from    uint64      // the version subscribed from, the records before it are skipped
+	next    uint64      // the sequence number of the next record, guarded by the lock of the changefeed
+	closed  bool        // the subscription is closed, guarded by the lock of the changefeed
+	dropped bool        // the subscription was dropped for lagging behind, guarded by the lock of the changefeed
+}
+
+// newChangefeed makes a new disabled changefeed.
+func newChangefeed() *changefeed {
+	feed := new(changefeed)
+	feed.lock = new(sync.Mutex)
+	feed.timeout = defaultSubscriberTimeout
+	feed.subs = make(map[*Subscription]bool)
+	feed.changed = make(chan struct{})
+	return feed
+}
+
+// EnableChangefeed starts recording the commits in the changefeed of the STM. The last
+// `capacity` records are kept for subscribing from the past versions, and a commit
+// waits after releasing the commit lock while the slowest subscription lags behind by
+// more than `capacity` records, slowing the transactions down to the pace of the
+// subscribers, see SetSubscriberTimeout. The commits made before the changefeed was enabled were never recorded,
+// the subscriptions from their versions are refused.
+func (stm *STM) EnableChangefeed(capacity int) {
+	if capacity < 1 {
+		capacity = 1
+	}
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	stm.feed.lock.Lock()
+	defer stm.feed.lock.Unlock()
+	if stm.feed.capacity == 0 {
+		stm.feed.trimmed = stm.version // as if the records up to now were dropped
+	}
+	stm.feed.capacity = capacity
+}
+
+// SetSubscriberTimeout sets how long a commit waits for the subscriptions lagging behind
+// by more than the capacity of the changefeed, 10 seconds by default. The subscriptions
+// still lagging behind then are dropped, their Next returns ErrSubscriptionDropped, so
+// a stalled subscriber can't hold the commits up for longer. A commit also stops waiting
+// when the context of its transaction is done, see STM.DoContext.
+func (stm *STM) SetSubscriberTimeout(timeout time.Duration) {
+	stm.feed.lock.Lock()
+	defer stm.feed.lock.Unlock()
+	stm.feed.timeout = timeout
+}
+
+// Subscribe subscribes to the changefeed of the STM from the `version` on, the records
+// of the commits at the version or later are delivered in order. Subscribe from
+// Version()+1 for the future commits only, the records of the commits before a future
+// version are skipped. It returns ErrVersionNotRetained when the records from the
+// version are no longer kept, see EnableChangefeed.
+func (stm *STM) Subscribe(version uint64) (*Subscription, error) {
+	feed := stm.feed
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+
+	if feed.capacity == 0 {
+		return nil, errors.New("stm: the changefeed is not enabled, see EnableChangefeed")
+	}
+	if version <= feed.trimmed {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotRetained, version)
+	}
+
+	sub := new(Subscription)
+	sub.feed = feed
+	sub.from = version
+	sub.next = feed.base + uint64(len(feed.records))
+	for i, record := range feed.records {
+		if record.Version >= version {
+			sub.next = feed.base + uint64(i)
+			break
+		}
+	}
+	feed.subs[sub] = true
+	return sub, nil
+}
+
+// Next gives the next change record, blocking until there is one. It returns the
+// error of the context when it is done first, ErrSubscriptionClosed, or
+// ErrSubscriptionDropped once the subscription has been dropped for lagging behind.
+func (sub *Subscription) Next(ctx context.Context) (ChangeRecord, error) {
+	feed := sub.feed
+	for {
+		feed.lock.Lock()
+		if sub.closed {
+			feed.lock.Unlock()
+			return ChangeRecord{}, ErrSubscriptionClosed
+		}
+		if sub.dropped {
+			feed.lock.Unlock()
+			return ChangeRecord{}, ErrSubscriptionDropped
+		}
+		if i := sub.next - feed.base; i < uint64(len(feed.records)) {
+			record := feed.records[i]
+			sub.next++
+			feed.trim()
+			feed.signal()
+			feed.lock.Unlock()
+			if record.Version < sub.from {
+				continue // subscribed from a future version, it hasn't come yet
+			}
+			return record.copy(), nil
+		}
+		changed := feed.changed
+		feed.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ChangeRecord{}, ctx.Err()
+		}
+	}
+}
+
+// Close closes the subscription, the commits no longer wait for it.
+func (sub *Subscription) Close() {
+	feed := sub.feed
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(feed.subs, sub)
+	feed.trim()
+	feed.signal()
+}
+
+// copy gives a copy of the record with copies of the values, for a subscriber.
+func (record ChangeRecord) copy() ChangeRecord {
+	changes := make([]Change, len(record.Changes))
+	for i, change := range record.Changes {
+		changes[i] = change
+		if change.Old != nil {
+			changes[i].Old = change.Old.MakeCopy()
+		}
+		changes[i].New = change.New.MakeCopy()
+	}
+	record.Changes = changes
+	return record
+}
+
+// recordCreation records the creation of the memory cells outside transactions in the
+// changefeed, at the version it made. The caller must hold the commit lock.
+func (stm *STM) recordCreation(memCells ...*memoryCell) {
+	if !stm.feed.enabled() {
+		return
+	}
+	changes := make([]Change, 0, len(memCells))
+	for _, memCell := range memCells {
+		if memCell.compute != nil {
+			continue // computed TVars are derived, the version is still recorded
+		}
+		changes = append(changes, Change{Cell: memCell.id, Name: memCell.name, Created: true, New: memCell.data})
+	}
+	stm.feed.append(ChangeRecord{Version: stm.version, Changes: changes})
+}
+
+// enabled checks if the changefeed is recording the commits.
+func (feed *changefeed) enabled() bool {
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+	return feed.capacity > 0
+}
+
+// append adds the record to the changefeed. The caller must hold the commit lock, so
+// the records are added in the order of the versions. It never waits, see throttle.
+func (feed *changefeed) append(record ChangeRecord) {
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+
+	feed.records = append(feed.records, record)
+	feed.trim()
+	feed.signal()
+}
+
+// throttle waits while the slowest subscription lags behind by more than the capacity
+// of the changefeed, until the context is done or for the timeout of the changefeed at
+// most. The subscriptions still lagging behind after the timeout are dropped.
+func (feed *changefeed) throttle(ctx context.Context) {
+	var expired <-chan time.Time
+	for {
+		feed.lock.Lock()
+		if feed.lag() <= feed.capacity {
+			feed.lock.Unlock()
+			return
+		}
+		if expired == nil {
+			timer := time.NewTimer(feed.timeout)
+			defer timer.Stop()
+			expired = timer.C
+		}
+		changed := feed.changed
+		feed.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return
+		case <-expired:
+			feed.dropLagging()
+			return
+		}
+	}
+}
+
+// dropLagging drops the subscriptions lagging behind by more than the capacity of the
+// changefeed, the records kept for them can be trimmed.
+func (feed *changefeed) dropLagging() {
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+
+	end := feed.base + uint64(len(feed.records))
+	for sub := range feed.subs {
+		if int(end-sub.next) > feed.capacity {
+			sub.dropped = true
+			delete(feed.subs, sub)
+		}
+	}
+	feed.trim()
+	feed.signal()
+}
+
+// lag gives the number of records the slowest subscription has yet to consume. The
+// caller must hold the lock of the changefeed.
+func (feed *changefeed) lag() int {
+	end := feed.base + uint64(len(feed.records))
+	lag := 0
+	for sub := range feed.subs {
+		if n := int(end - sub.next); n > lag {
+			lag = n
+		}
+	}
+	return lag
+}
+
+// trim drops the records consumed by every subscription, except the last `capacity`
+// records. The caller must hold the lock of the changefeed.
+func (feed *changefeed) trim() {
+	keep := feed.capacity
+	if lag := feed.lag(); lag > keep {
+		keep = lag
+	}
+	if drop := len(feed.records) - keep; drop > 0 {
+		feed.trimmed = feed.records[drop-1].Version
+		clear(feed.records[:drop]) // the values of the dropped records can be collected
+		feed.records = feed.records[drop:]
+		feed.base += uint64(drop)
+	}
+}
+
+// signal wakes up the subscriptions and the commits waiting on the changefeed. The
+// caller must hold the lock of the changefeed.
+func (feed *changefeed) signal() {
+	close(feed.changed)
+	feed.changed = make(chan struct{})
+}
@@ -0,0 +1,194 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// changefeed_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:31:30 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:30:38 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscribeBeforeTheChangefeedIsRefused(t *testing.T) {
+	s := New()
+	balance := s.NewTVar(counterValue(0))
+	for i := 1; i <= 3; i++ {
+		s.Do(func(tx *Transaction) bool { return tx.Write(balance, counterValue(i)) })
+	}
+
+	s.EnableChangefeed(8)
+	if _, err := s.Subscribe(1); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("subscribed from a commit that was never recorded, error %v", err)
+	}
+	sub, err := s.Subscribe(s.Version() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+}
+
+func TestSubscribeFromAFutureVersion(t *testing.T) {
+	s := New()
+	s.EnableChangefeed(8)
+	balance := s.NewTVar(counterValue(0))
+	from := s.Version() + 3
+	sub, err := s.Subscribe(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	for i := 1; i <= 4; i++ {
+		s.Do(func(tx *Transaction) bool { return tx.Write(balance, counterValue(i)) })
+	}
+	record, err := sub.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Version != from || !record.Changes[0].New.IsEqual(counterValue(3)) {
+		t.Errorf("first record at version %d, want %d", record.Version, from)
+	}
+}
+
+// commitsIn commits the `values` to the memory cell one after the other, in the
+// background, and sends the index of every commit done.
+func commitsIn(s *STM, tVar TVar, values ...int) <-chan int {
+	done := make(chan int, len(values))
+	go func() {
+		for i, value := range values {
+			set(s, tVar, counterValue(value))
+			done <- i
+		}
+	}()
+	return done
+}
+
+// expectCommit checks the commit `i` is done, or isn't done yet when `i` is negative.
+func expectCommit(t *testing.T, done <-chan int, i int) {
+	t.Helper()
+	if i < 0 {
+		select {
+		case i := <-done:
+			t.Fatalf("commit %d wasn't held up by the lagging subscription", i)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return
+	}
+	select {
+	case got := <-done:
+		if got != i {
+			t.Fatalf("commit %d done, want commit %d", got, i)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("commit %d is still held up", i)
+	}
+}
+
+func TestCommitsWaitForTheLaggingSubscription(t *testing.T) {
+	s := New()
+	s.EnableChangefeed(2)
+	s.SetSubscriberTimeout(time.Hour)
+	balance := s.NewTVar(counterValue(0))
+	sub, err := s.Subscribe(s.Version() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	done := commitsIn(s, balance, 1, 2, 3, 4)
+	expectCommit(t, done, 0)
+	expectCommit(t, done, 1)
+	expectCommit(t, done, -1) // 3 records behind, over the capacity
+
+	for i := 1; i <= 2; i++ {
+		record, err := sub.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		change := record.Changes[0]
+		if change.Old != counterValue(i-1) || change.New != counterValue(i) {
+			t.Errorf("record %d changed %v to %v, want %d to %d", i, change.Old, change.New, i-1, i)
+		}
+		expectCommit(t, done, i+1)
+	}
+}
+
+func TestCommitStopsWaitingWhenItsContextIsDone(t *testing.T) {
+	s := New()
+	s.EnableChangefeed(1)
+	s.SetSubscriberTimeout(time.Hour)
+	balance := s.NewTVar(counterValue(0))
+	sub, _ := s.Subscribe(s.Version() + 1)
+	defer sub.Close()
+	set(s, balance, counterValue(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.DoContext(ctx, func(t *Transaction) bool { return t.Write(balance, counterValue(2)) })
+	if err != nil || countOf(balance) != 2 {
+		t.Errorf("the commit holds %v, error %v, want it committed", countOf(balance), err)
+	}
+	if _, err := sub.Next(context.Background()); err != nil {
+		t.Errorf("the subscription was dropped for the context of a commit: %v", err)
+	}
+}
+
+func TestLaggingSubscriptionIsDropped(t *testing.T) {
+	s := New()
+	s.EnableChangefeed(1)
+	s.SetSubscriberTimeout(20 * time.Millisecond)
+	balance := s.NewTVar(counterValue(0))
+	lagging, _ := s.Subscribe(s.Version() + 1)
+	defer lagging.Close()
+
+	done := commitsIn(s, balance, 1, 2)
+	expectCommit(t, done, 0)
+	expectCommit(t, done, 1)
+	if _, err := lagging.Next(context.Background()); !errors.Is(err, ErrSubscriptionDropped) {
+		t.Errorf("Next of the lagging subscription: got %v, want ErrSubscriptionDropped", err)
+	}
+
+	sub, err := s.Subscribe(s.Version())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if record, err := sub.Next(context.Background()); err != nil || record.Version != s.Version() {
+		t.Errorf("subscribed again at %d, got %+v, error %v", s.Version(), record, err)
+	}
+}
@@ -0,0 +1,165 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// checkpoint.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:56:26 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:29:44 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// checkpointSuffix is appended to the path of the log for the path of its checkpoint.
+const checkpointSuffix = ".checkpoint"
+
+// checkpointTempSuffix is appended to the path of the log for the path a checkpoint is
+// written at before it replaces the previous checkpoint.
+const checkpointTempSuffix = ".checkpoint.tmp"
+
+// WithCheckpointInterval sets a checkpoint to be taken every `interval` in the background,
+// see STM.Checkpoint.
+func WithCheckpointInterval(interval time.Duration) Option {
+	return func(opts *persistOptions) {
+		opts.checkpointInterval = interval
+	}
+}
+
+// Checkpoint writes the contents of all the persisted memory cells of a persisted STM
+// to the checkpoint next to its write-ahead log and truncates the log, the log only
+// holds the commits after the last checkpoint. The checkpoint is taken under the commit
+// lock, so it is consistent.
+//
+// The checkpoint is written aside and renamed over the previous one, a crash at any
+// point leaves the previous checkpoint and the log or the new checkpoint behind. The
+// log records already in the checkpoint are skipped when they are replayed. The
+// persisted memory cells must take no more than 1 GiB encoded, the largest record.
+func (stm *STM) Checkpoint() error {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if stm.wal == nil {
+		return errors.New("stm: checkpoint of an STM that isn't persisted")
+	}
+
+	stm.memoryLock.RLock()
+	memCells := make([]*memoryCell, len(stm.memory))
+	values := make([]Value, len(stm.memory))
+	for i, memCell := range stm.memory {
+		memCells[i], values[i] = memCell, memCell.read()
+	}
+	stm.memoryLock.RUnlock()
+
+	entries, err := stm.wal.encodeEntries(memCells, values)
+	if err != nil {
+		return err
+	}
+	return stm.wal.checkpoint(encodeRecord(stm.version, entries))
+}
+
+// checkpointPeriodically takes a checkpoint every `interval` until `done` is closed.
+func (stm *STM) checkpointPeriodically(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := stm.Checkpoint(); err != nil && err != ErrClosed {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// checkpoint replaces the checkpoint with the record and truncates the log. The caller
+// must hold the commit lock, so no records are appended meanwhile.
+func (w *wal) checkpoint(payload []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if err := checkFrame(payload); err != nil {
+		return err
+	}
+
+	temp := w.path + checkpointTempSuffix
+	if err := w.writeCheckpoint(temp, payload); err != nil {
+		w.opts.fs.Remove(temp)
+		return fmt.Errorf("stm: writing the checkpoint: %w", err)
+	}
+	if err := w.opts.fs.Rename(temp, w.path+checkpointSuffix); err != nil {
+		w.opts.fs.Remove(temp)
+		return fmt.Errorf("stm: writing the checkpoint: %w", err)
+	}
+	syncDir(w.opts.fs, w.path)
+
+	// the log is in the checkpoint now, it can be emptied
+	if err := w.file.Truncate(0); err != nil {
+		w.err = fmt.Errorf("stm: truncating the write-ahead log: %w", err)
+		return w.err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		w.err = fmt.Errorf("stm: truncating the write-ahead log: %w", err)
+		return w.err
+	}
+	w.size = 0
+	w.dirty = true
+	return w.sync()
+}
+
+// writeCheckpoint writes the record to the file at `path` and flushes it to stable storage.
+func (w *wal) writeCheckpoint(path string, payload []byte) error {
+	file, err := w.opts.fs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(encodeFrame(payload)); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,194 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// codec.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:57:45 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:24:21 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrNoCodec is returned by a Codec for the values it doesn't know how to encode or
+// decode. The memory cells holding such values are not persisted.
+var ErrNoCodec = errors.New("stm: no codec for the value")
+
+// Codec encodes and decodes the values of the memory cells, for persisting them in the
+// write-ahead log, snapshotting them or sending them over the wire.
+type Codec interface {
+	// Encode encodes the `value`. The type is a tag for Decode to recognize the
+	// encoding by. It returns ErrNoCodec for the values it doesn't encode.
+	Encode(value Value) (typ string, data []byte, err error)
+
+	// Decode decodes a value encoded by Encode with the type `typ`.
+	Decode(typ string, data []byte) (Value, error)
+}
+
+// DefaultCodecs is the codec registry used when no codec is given, e.g. by Open.
+var DefaultCodecs = NewCodecRegistry()
+
+func init() {
+	DefaultCodecs.Register("stm.counter", BinaryEncoding[counterValue]())
+}
+
+// Encoding is how the values of a Go type are encoded, see JSONEncoding, GobEncoding
+// and BinaryEncoding.
+type Encoding struct {
+	Type   reflect.Type                      // the Go type of the values
+	Encode func(value Value) ([]byte, error) // encodes a value of the type
+	Decode func(data []byte) (Value, error)  // decodes a value of the type
+}
+
+// CodecRegistry is a Codec mapping the Go types of the values to their encodings.
+// Every type is registered with a name, the name is the type Encode tags the encoded
+// values with, so the names must be stable across the versions of a program.
+type CodecRegistry struct {
+	lock   *sync.RWMutex        // guards the registrations
+	byType map[reflect.Type]int // the registrations by the Go type of the values
+	byName map[string]int       // the registrations by name
+	names  []string             // the names of the registrations
+	encs   []Encoding           // the encodings of the registrations
+}
+
+// NewCodecRegistry makes a new empty codec registry.
+func NewCodecRegistry() *CodecRegistry {
+	r := new(CodecRegistry)
+	r.lock = new(sync.RWMutex)
+	r.byType = make(map[reflect.Type]int)
+	r.byName = make(map[string]int)
+	return r
+}
+
+// Register registers the encoding for its type with the `name`. Like gob.Register it
+// panics when the name or the type is already registered, registrations are expected
+// to be made by the init functions of the packages.
+func (r *CodecRegistry) Register(name string, enc Encoding) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.byName[name]; ok {
+		panic(fmt.Sprintf("stm: codec %q registered twice", name))
+	}
+	if _, ok := r.byType[enc.Type]; ok {
+		panic(fmt.Sprintf("stm: codec for %v registered twice", enc.Type))
+	}
+	r.byType[enc.Type] = len(r.encs)
+	r.byName[name] = len(r.encs)
+	r.names = append(r.names, name)
+	r.encs = append(r.encs, enc)
+}
+
+// Encode makes CodecRegistry conform to the Codec interface.
+func (r *CodecRegistry) Encode(value Value) (string, []byte, error) {
+	r.lock.RLock()
+	i, ok := r.byType[reflect.TypeOf(value)]
+	r.lock.RUnlock()
+	if !ok {
+		return "", nil, ErrNoCodec
+	}
+
+	data, err := r.encs[i].Encode(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return r.names[i], data, nil
+}
+
+// Decode makes CodecRegistry conform to the Codec interface.
+func (r *CodecRegistry) Decode(typ string, data []byte) (Value, error) {
+	r.lock.RLock()
+	i, ok := r.byName[typ]
+	r.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrNoCodec, typ)
+	}
+	return r.encs[i].Decode(data)
+}
+
+// ------------------------------------------------------------------------
+
+// JSONEncoding encodes the values of the type T as JSON with the encoding/json package.
+func JSONEncoding[T Value]() Encoding {
+	return Encoding{
+		Type: reflect.TypeFor[T](),
+		Encode: func(value Value) ([]byte, error) {
+			return json.Marshal(value)
+		},
+		Decode: func(data []byte) (Value, error) {
+			return decodeValue[T](func(target interface{}) error {
+				return json.Unmarshal(data, target)
+			})
+		},
+	}
+}
+
+// GobEncoding encodes the values of the type T with the encoding/gob package. Every
+// value is encoded on its own, with the description of its type.
+func GobEncoding[T Value]() Encoding {
+	return Encoding{
+		Type: reflect.TypeFor[T](),
+		Encode: func(value Value) ([]byte, error) {
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(value)
+			return buf.Bytes(), err
+		},
+		Decode: func(data []byte) (Value, error) {
+			return decodeValue[T](func(target interface{}) error {
+				return gob.NewDecoder(bytes.NewReader(data)).Decode(target)
+			})
+		},
+	}
+}
+
+// decodeValue decodes a new value of the type T with `unmarshal`. When T is a pointer
+// the value it points to is allocated and unmarshalled into, otherwise the T is.
+func decodeValue[T Value](unmarshal func(target interface{}) error) (Value, error) {
+	var value T
+	var target interface{} = &value
+	if typ := reflect.TypeFor[T](); typ.Kind() == reflect.Pointer {
+		value = reflect.New(typ.Elem()).Interface().(T)
+		target = value
+	}
+	if err := unmarshal(target); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,151 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// codec_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:24:21 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:24:21 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// record is a value encoded field by field by BinaryEncoding.
+type record struct {
+	Name    string
+	Count   int
+	Size    uint16
+	Ratio   float64
+	Small   float32
+	Active  bool
+	Tags    []string
+	Digest  [4]byte
+	Limits  map[string]int32
+	Next    *record
+	Counter counterValue
+	Label   label
+}
+
+func (r *record) MakeCopy() Value          { c := *r; return &c }
+func (r *record) IsEqual(other Value) bool { return reflect.DeepEqual(r, other) }
+
+// hidden is a value with unexported fields, BinaryEncoding can't encode it field by field.
+type hidden struct{ n int }
+
+func (h hidden) MakeCopy() Value          { return h }
+func (h hidden) IsEqual(other Value) bool { return other == h }
+
+func TestBinaryEncodingRoundTrips(t *testing.T) {
+	enc := BinaryEncoding[*record]()
+	value := &record{
+		Name: "a", Count: -3, Size: 300, Ratio: 0.5, Small: 1.25, Active: true,
+		Tags:    []string{"x", "", "yz"},
+		Digest:  [4]byte{1, 2, 3, 4},
+		Limits:  map[string]int32{"low": -1, "high": 1 << 20},
+		Next:    &record{Name: "b"},
+		Counter: 9,
+	}
+	data, err := enc.Encode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := enc.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, value) {
+		t.Errorf("decoded %+v, want %+v", decoded, value)
+	}
+
+	for cut := 0; cut < len(data); cut++ {
+		if _, err := enc.Decode(data[:cut]); err == nil {
+			t.Errorf("decoded the first %d of %d bytes", cut, len(data))
+		}
+	}
+	if _, err := enc.Decode(append(data, 0)); !errors.Is(err, errMalformed) {
+		t.Errorf("decoding with a byte left over: got %v, want malformed data", err)
+	}
+}
+
+func TestBinaryEncodingIsCompact(t *testing.T) {
+	data, err := BinaryEncoding[counterValue]().Encode(counterValue(-42))
+	if err != nil || len(data) != 1 {
+		t.Errorf("encoded a small count in %d bytes (error %v), want a single byte", len(data), err)
+	}
+	data, _ = BinaryEncoding[*record]().Encode(&record{Name: "ab"})
+	// a byte for the pointer, the name in 3 bytes, then a byte for every other field,
+	// 4 for the digest, 8 and 4 for the floats
+	if want := 1 + 3 + 1 + 1 + 8 + 4 + 1 + 1 + 4 + 1 + 1 + 1 + 1; len(data) != want {
+		t.Errorf("encoded a record in %d bytes, want %d", len(data), want)
+	}
+}
+
+func TestBinaryEncodingPanicsForUnexportedFields(t *testing.T) {
+	defer func() {
+		if msg, _ := recover().(string); !strings.Contains(msg, "unexported field n") {
+			t.Errorf("got %q, want a panic naming the unexported field", msg)
+		}
+	}()
+	BinaryEncoding[hidden]()
+}
+
+func TestCodecRegistry(t *testing.T) {
+	r := NewCodecRegistry()
+	r.Register("test.record", BinaryEncoding[*record]())
+	r.Register("test.label", JSONEncoding[label]())
+
+	typ, data, err := r.Encode(&record{Name: "a"})
+	if err != nil || typ != "test.record" {
+		t.Fatalf("encoded as %q (error %v), want test.record", typ, err)
+	}
+	if v, err := r.Decode(typ, data); err != nil || v.(*record).Name != "a" {
+		t.Errorf("decoded %v (error %v)", v, err)
+	}
+	if _, _, err := r.Encode(counterValue(1)); !errors.Is(err, ErrNoCodec) {
+		t.Errorf("encoding an unregistered type: got %v, want ErrNoCodec", err)
+	}
+	if _, err := r.Decode("test.missing", nil); !errors.Is(err, ErrNoCodec) {
+		t.Errorf("decoding an unregistered name: got %v, want ErrNoCodec", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("a name was registered twice")
+		}
+	}()
+	r.Register("test.record", GobEncoding[counterValue]())
+}
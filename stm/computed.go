@@ -0,0 +1,152 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// computed.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:42:52 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:03:52 GMT-0700 (PDT)
+//
+
+package stm
+
+// computedState is the contents of the memory cell of a computed TVar.
+type computedState struct {
+	value Value // the cached value, nil when stale
+	stale bool  // flag showing if the cached value needs to be computed again
+}
+
+// MakeCopy makes computedState conform to the Value interface.
+func (s *computedState) MakeCopy() Value {
+	ns := new(computedState)
+	if s.value != nil {
+		ns.value = s.value.MakeCopy()
+	}
+	ns.stale = s.stale
+	return ns
+}
+
+// IsEqual checks the equality between two computed states.
+func (s *computedState) IsEqual(v Value) bool {
+	vv, ok := v.(*computedState)
+	if !ok {
+		return false
+	}
+	if vv.stale || s.stale {
+		return vv.stale == s.stale
+	}
+	return vv.value.IsEqual(s.value)
+}
+
+// computation is the result of computing a computed TVar within a transaction.
+type computation struct {
+	value Value                // the computed value
+	deps  map[*memoryCell]bool // the memory cells read while computing the value
+}
+
+// NewComputed creates a computed TVar. Its value is the result of `fn`, which reads other
+// TVars -- its dependencies -- through the transaction it is given. The computed TVar is
+// read with Transaction.Read like any other TVar, but it can't be written to.
+//
+// The value is cached in the STM. A commit changing any of the dependencies invalidates
+// the cached value, and it is computed again by the next transaction that reads it, e.g.
+// the total of the balances of all the accounts is only summed up again after a deposit.
+//
+// Creating the computed TVar is a commit of its own like NewTVar. Computed TVars are left
+// out of snapshots, the changefeed and the write-ahead log, they are derived from the
+// other memory cells.
+func (stm *STM) NewComputed(fn func(*Transaction) Value) TVar {
+	return stm.newTVar("", func(self TVar) Value {
+		self.(*memoryCell).compute = fn
+		state := new(computedState)
+		state.stale = true
+		return state
+	})
+}
+
+// track records the memory cell as a dependency of the computed TVars being computed.
+func (t *Transaction) track(memCell *memoryCell) {
+	for _, deps := range t.tracking {
+		deps[memCell] = true
+	}
+}
+
+// readComputed reads the value of the computed TVar's memory cell. The cached value is
+// used when it is valid and the transaction hasn't written anything, otherwise the value
+// is computed within the transaction, reading its dependencies.
+func (t *Transaction) readComputed(memCell *memoryCell) Value {
+	state := memCell.read().(*computedState)
+	if !state.stale && len(t.writeQuarantine) == 0 && len(t.commutes) == 0 {
+		if _, ok := t.readQuarantine[memCell]; !ok {
+			t.readQuarantine[memCell] = state // invalidating the cache fails the transaction
+		}
+		return state.value.MakeCopy()
+	}
+
+	c := new(computation)
+	c.deps = make(map[*memoryCell]bool)
+	t.tracking = append(t.tracking, c.deps)
+	c.value = memCell.compute(t)
+	t.tracking = t.tracking[:len(t.tracking)-1]
+
+	t.recomputed[memCell] = c
+	return c.value.MakeCopy()
+}
+
+// cacheComputed caches the value computed by a committing transaction in the computed
+// TVar's memory cell. It must be called while holding the commit lock.
+func (stm *STM) cacheComputed(memCell *memoryCell, c *computation) {
+	state := new(computedState)
+	state.value = c.value
+	memCell.write(state)
+	for dep := range c.deps {
+		if stm.dependents[dep] == nil {
+			stm.dependents[dep] = make(map[*memoryCell]bool)
+		}
+		stm.dependents[dep][memCell] = true
+	}
+}
+
+// invalidateDependents marks the cached values of the computed TVars depending on the
+// memory cell as stale, and in turn the computed TVars depending on those. It must be
+// called while holding the commit lock.
+func (stm *STM) invalidateDependents(memCell *memoryCell) {
+	dependents := stm.dependents[memCell]
+	delete(stm.dependents, memCell)
+	for dependent := range dependents {
+		if dependent.read().(*computedState).stale {
+			continue
+		}
+		state := new(computedState)
+		state.stale = true
+		dependent.write(state)
+		stm.invalidateDependents(dependent)
+	}
+}
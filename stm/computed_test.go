@@ -0,0 +1,130 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// computed_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:03:52 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:03:52 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTotal creates a computed TVar summing up the counts in the `tVars`, and gives the
+// number of times it has been computed.
+func newTotal(s *STM, tVars ...TVar) (TVar, *int) {
+	computed := new(int)
+	total := s.NewComputed(func(t *Transaction) Value {
+		*computed++
+		var sum counterValue
+		for _, tVar := range tVars {
+			sum += t.Read(tVar).(counterValue)
+		}
+		return sum
+	})
+	return total, computed
+}
+
+// readTotal reads the computed TVar in a transaction of its own.
+func readTotal(s *STM, total TVar) (value Value) {
+	s.Do(func(t *Transaction) bool {
+		value = t.Read(total)
+		return true
+	})
+	return value
+}
+
+func TestComputedIsCachedUntilADependencyChanges(t *testing.T) {
+	s := New()
+	a, b := s.NewTVar(counterValue(1)), s.NewTVar(counterValue(2))
+	total, computed := newTotal(s, a, b)
+
+	for i := 0; i < 3; i++ {
+		if got := readTotal(s, total); got != counterValue(3) {
+			t.Fatalf("total: got %v, want 3", got)
+		}
+	}
+	if *computed != 1 {
+		t.Errorf("computed %d times for 3 reads without changes, want once", *computed)
+	}
+
+	set(s, b, counterValue(5))
+	if got := readTotal(s, total); got != counterValue(6) {
+		t.Errorf("total after a change: got %v, want 6", got)
+	}
+	if *computed != 2 {
+		t.Errorf("computed %d times, want the change to invalidate the cache once", *computed)
+	}
+}
+
+func TestComputedSeesTheTransactionsWrites(t *testing.T) {
+	s := New()
+	a := s.NewTVar(counterValue(1))
+	total, _ := newTotal(s, a)
+
+	s.Do(func(tx *Transaction) bool {
+		tx.Write(a, counterValue(10))
+		if got := tx.Read(total); got != counterValue(10) {
+			t.Errorf("total after a write in the transaction: got %v, want 10", got)
+		}
+		if tx.Write(total, counterValue(0)) || tx.Commute(total, func(v Value) Value { return v }) {
+			t.Error("a computed TVar was written to")
+		}
+		return true
+	})
+}
+
+func TestNewComputedIsACommit(t *testing.T) {
+	s := New()
+	s.NewTVar(counterValue(1))
+	before := s.Version()
+	s.NewComputed(func(t *Transaction) Value { return counterValue(0) })
+	if after := s.Version(); after != before+1 {
+		t.Errorf("NewComputed advanced the version from %d to %d, want a commit", before, after)
+	}
+
+	snap := s.Snapshot()
+	if len(snap.Cells) != 1 {
+		t.Errorf("the snapshot has %d memory cells, want the computed TVar left out", len(snap.Cells))
+	}
+
+	s.SetReadOnly(true)
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("NewComputed on a read-only STM: got %v, want ErrReadOnly", err)
+		}
+	}()
+	s.NewComputed(func(t *Transaction) Value { return counterValue(0) })
+}
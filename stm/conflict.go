@@ -0,0 +1,142 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// conflict.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:44:42 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:48:32 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError is the error of a commit that failed because other transactions changed
+// the memory cells the transaction had read. It is given to the contention manager, and
+// a transaction the contention manager gives up on is aborted with it.
+type ConflictError struct {
+	Transaction uint64     // the ID of the transaction that failed to commit
+	Label       string     // the label of the transaction that failed to commit
+	Attempt     int        // the attempt of the transaction that failed to commit, starting at 1
+	Conflicts   []Conflict // the memory cells that were changed
+}
+
+// Conflict is a memory cell read by a transaction that was changed before it could commit.
+type Conflict struct {
+	Cell    string // the ID of the memory cell
+	Name    string // the name of the memory cell, empty if none
+	Writer  uint64 // the ID of the transaction that last wrote the memory cell
+	Version uint64 // the version of the STM after that write
+}
+
+// Error makes ConflictError conform to the error interface.
+func (e *ConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		conflicts[i] = fmt.Sprintf("memory cell %s written by transaction %d at version %d", c.Label(), c.Writer, c.Version)
+	}
+	return fmt.Sprintf("stm: transaction %d %q conflicted on attempt %d: %s",
+		e.Transaction, e.Label, e.Attempt, strings.Join(conflicts, ", "))
+}
+
+// Label gives the name of the conflicting memory cell, or its ID when it has no name.
+func (c Conflict) Label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Cell
+}
+
+// ContentionManager decides what happens to a transaction after its commit failed on a
+// conflict. The transaction is retried when it returns nil, it is aborted with the error
+// it returns otherwise. It may block to back off before the transaction is retried.
+type ContentionManager interface {
+	OnConflict(conflict *ConflictError) error
+}
+
+// ContentionManagerFunc is a function conforming to the ContentionManager interface.
+type ContentionManagerFunc func(conflict *ConflictError) error
+
+// OnConflict calls the function.
+func (fn ContentionManagerFunc) OnConflict(conflict *ConflictError) error {
+	return fn(conflict)
+}
+
+// MaxAttempts gives a contention manager that retries a transaction until it has been
+// attempted `n` times, and then aborts it with the last conflict.
+func MaxAttempts(n int) ContentionManager {
+	return ContentionManagerFunc(func(conflict *ConflictError) error {
+		if conflict.Attempt >= n {
+			return conflict
+		}
+		return nil
+	})
+}
+
+// SetContentionManager sets the contention manager of the STM. Without one, the
+// conflicting transactions are retried until they commit.
+func (stm *STM) SetContentionManager(cm ContentionManager) {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	stm.contention = cm
+}
+
+// contend hands the conflict to the contention manager of the STM. It returns the
+// error the transaction is to be aborted with, nil when it is to be retried.
+func (stm *STM) contend(conflict *ConflictError) error {
+	stm.acquireCommitLock()
+	cm := stm.contention
+	stm.releaseCommitLock()
+
+	if cm == nil {
+		return nil
+	}
+	return cm.OnConflict(conflict)
+}
+
+// conflictError makes the error for the conflicts of the transaction's current attempt.
+func (t *Transaction) conflictError(conflicts []Conflict) *ConflictError {
+	err := new(ConflictError)
+	err.Transaction = t.id
+	err.Label = t.label
+	err.Attempt = t.attempts
+	err.Conflicts = conflicts
+	return err
+}
+
+// conflict describes the memory cell as a conflict. It must be called while holding
+// the commit lock.
+func (memCell *memoryCell) conflict() Conflict {
+	return Conflict{Cell: memCell.id, Name: memCell.name, Writer: memCell.lastWriter, Version: memCell.lastVersion}
+}
@@ -0,0 +1,90 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// conflict_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:08:59 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:08:59 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConflictErrorReportsTheChangedCell(t *testing.T) {
+	s := New()
+	balance := s.NewNamedTVar("balance", counterValue(1))
+	s.SetContentionManager(MaxAttempts(1))
+
+	var writer uint64
+	err := s.DoLabelled("double", func(tx *Transaction) bool {
+		count := tx.Read(balance).(counterValue)
+		// a concurrent transaction changes the memory cell after it was read
+		s.Do(func(t *Transaction) bool {
+			writer = t.id
+			return t.Write(balance, counterValue(5))
+		})
+		return tx.Write(balance, count*2)
+	})
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Do: got %v, want a *ConflictError", err)
+	}
+	if conflict.Label != "double" || conflict.Attempt != 1 {
+		t.Errorf("conflict of %q on attempt %d, want double on attempt 1", conflict.Label, conflict.Attempt)
+	}
+	if len(conflict.Conflicts) != 1 {
+		t.Fatalf("%d conflicting memory cells, want 1", len(conflict.Conflicts))
+	}
+	c := conflict.Conflicts[0]
+	if c.Cell != balance.(*memoryCell).id || c.Label() != "balance" || c.Writer != writer || c.Version != s.Version() {
+		t.Errorf("conflict on %s (%s) written by %d at version %d, want balance written by %d at version %d",
+			c.Label(), c.Cell, c.Writer, c.Version, writer, s.Version())
+	}
+	if msg := err.Error(); !strings.Contains(msg, `"double"`) || !strings.Contains(msg, "memory cell balance") {
+		t.Errorf("the error %q doesn't name the transaction and the memory cell", msg)
+	}
+	if countOf(balance) != 5 {
+		t.Errorf("balance: got %v, want the concurrent write kept", countOf(balance))
+	}
+}
+
+func TestConflictOnAnUnnamedCellIsLabelledWithItsID(t *testing.T) {
+	c := Conflict{Cell: "cell-id"}
+	if c.Label() != "cell-id" {
+		t.Errorf("Label: got %q, want the ID", c.Label())
+	}
+}
@@ -0,0 +1,310 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// coordinator.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:10:47 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CrossTransaction is a transaction over several STMs, see DoAcross. It is made of a
+// Transaction on every STM it touches, its parts, committed together or not at all.
+type CrossTransaction struct {
+	ctx      context.Context       // the context the transaction is performed in
+	label    string                // the label of the transaction, the label of every part
+	attempts int                   // the number of times the action has been attempted
+	parts    map[*STM]*Transaction // the parts of the transaction, by STM
+	order    []*STM                // the STMs of the parts, in the order of their serial numbers
+}
+
+// DoAcross performs the transactional action as one transaction over all the STMs it
+// touches, see CrossTransaction.On, and waits for it to complete. It is how memory
+// cells living in different STMs, e.g. the accounts of different shards, are modified
+// atomically. It returns the error the transaction was aborted with, nil when it has
+// committed.
+//
+// The transaction is committed in two phases. The commit locks of the STMs are acquired
+// in the order of the STMs, so concurrent cross transactions never deadlock, then every
+// part is validated and prepared. Only once all the parts are prepared are they logged
+// and published, each part as a new version of its STM. A conflict in any of the parts
+// retries the whole transaction, under the contention manager of the STM it is in.
+// When a part can't be logged, the parts logged before it are taken back from the logs
+// of their STMs and the transaction is aborted, none of the parts is published or
+// recovered. The commit is atomic for the transactions of the STMs, but not across the
+// crashes of persisted STMs: each STM logs its part in its own write-ahead log, a crash
+// while the parts are logged recovers the parts logged before it only.
+func DoAcross(action func(*CrossTransaction) bool) error {
+	return DoAcrossContext(context.Background(), action)
+}
+
+// DoAcrossContext is DoAcross with a context, see STM.DoContext.
+func DoAcrossContext(ctx context.Context, action func(*CrossTransaction) bool) error {
+	ct := new(CrossTransaction)
+	ct.ctx = ctx
+	ct.label, _ = ctx.Value(labelKey{}).(string)
+	ct.parts = make(map[*STM]*Transaction)
+	return ct.run(action)
+}
+
+// On gives the part of the transaction on the STM, the Transaction the memory cells of
+// the STM are read and written with. The part is begun the first time it is asked for.
+func (ct *CrossTransaction) On(stm *STM) *Transaction {
+	if t, ok := ct.parts[stm]; ok {
+		return t
+	}
+	t := newTransaction(stm, nil)
+	t.ctx = ct.ctx
+	t.label = ct.label
+	stm.beginTransaction(t)
+	t.attempts = ct.attempts
+
+	ct.parts[stm] = t
+	ct.order = append(ct.order, stm)
+	sort.Slice(ct.order, func(i, j int) bool { return ct.order[i].serial < ct.order[j].serial })
+	return t
+}
+
+// Attempt gives the number of the current attempt of the transaction, starting at 1.
+func (ct *CrossTransaction) Attempt() int {
+	return ct.attempts
+}
+
+// run runs the action until the transaction commits or is aborted, like
+// Transaction.run does for a transaction on a single STM.
+func (ct *CrossTransaction) run(action func(*CrossTransaction) bool) (err error) {
+	defer func() {
+		for _, stm := range ct.order {
+			t := ct.parts[stm]
+			if err != nil {
+				t.err = err // the parts of an action that panicked have been abandoned already
+			}
+			stm.endTransaction(t)
+		}
+	}()
+
+	for {
+		ct.attempts++
+		for _, t := range ct.parts {
+			t.attempts = ct.attempts
+		}
+		status, retry := ct.attempt(action)
+		if retry && !ct.hasRead() {
+			// nothing read, nothing to wait for, give up on the transaction
+			err = ErrRetryWithoutReads
+			ct.abortAttempt(AbortRetry, err)
+			ct.rollback()
+			return err
+		}
+		if retry {
+			// the transaction is waiting for its read sets to change
+			ct.abortAttempt(AbortRetry, nil)
+			err = ct.awaitChange()
+			ct.rollback()
+			if err != nil {
+				// the context was done before anything changed, give up on the transaction
+				return err
+			}
+			continue
+		}
+		if !status {
+			// failed to execute the action
+			ct.abortAttempt(AbortFailed, nil)
+			ct.rollback()
+			continue
+		}
+		stm, err := ct.commit()
+		if conflict, ok := err.(*ConflictError); ok {
+			// failed to commit, the contention manager of the STM decides if it is retried
+			ct.abortAttempt(AbortConflict, conflict)
+			ct.rollback()
+			if err := stm.contend(conflict); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			// the commit was rejected, give up on the transaction
+			ct.abortAttempt(AbortInvalid, err)
+			ct.rollback()
+			return err
+		}
+		break
+	}
+
+	for _, stm := range ct.order {
+		t := ct.parts[stm]
+		stm.notifyWatchers(t.changes) // delivered after the commit locks have been released
+		if t.record != nil {
+			stm.feed.throttle(ct.ctx) // waits for the subscribers to catch up
+		}
+		t.changes = nil
+	}
+	return nil
+}
+
+// attempt runs the action once. It reports if the action asked for the transaction to
+// be retried by calling Retry on one of the parts.
+func (ct *CrossTransaction) attempt(action func(*CrossTransaction) bool) (status bool, retry bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(retrySignal); !ok {
+				for _, t := range ct.parts {
+					t.abandon(r)
+				}
+				panic(r) // not ours to handle
+			}
+			status, retry = false, true
+		}
+	}()
+	return action(ct), false
+}
+
+// commit commits all the parts of the transaction, or none of them. It returns the
+// error of the part that couldn't be committed and the STM of the part.
+func (ct *CrossTransaction) commit() (*STM, error) {
+	var stms []*STM
+	for _, stm := range ct.order {
+		if !ct.parts[stm].isEmpty() {
+			stms = append(stms, stm) // the parts left empty by the last attempt commit nothing
+		}
+	}
+
+	// the commit locks are acquired in the order of the STMs
+	for _, stm := range stms {
+		waitStart := time.Now()
+		stm.acquireCommitLock()
+		stm.commitLockWaited(time.Since(waitStart))
+	}
+	defer func() {
+		for i := len(stms) - 1; i >= 0; i-- {
+			stms[i].releaseCommitLock()
+		}
+	}()
+
+	// prepare: every part is validated before any of them is written
+	newValues := make([]map[*memoryCell]Value, len(stms))
+	for i, stm := range stms {
+		values, err := ct.parts[stm].prepare()
+		if err != nil {
+			return stm, err
+		}
+		newValues[i] = values
+	}
+
+	// commit: the parts are logged, then published
+	offsets := make(map[*STM]int64, len(stms)) // the ends of the logs before the parts logged
+	for i, stm := range stms {
+		if stm.readOnly {
+			continue
+		}
+		offset := stm.logOffset()
+		if err := stm.logCommit(newValues[i], ct.parts[stm].newCells); err != nil {
+			for logged, offset := range offsets {
+				logged.unlog(offset) // the parts logged so far are aborted too
+			}
+			return stm, err
+		}
+		offsets[stm] = offset
+	}
+	for i, stm := range stms {
+		t := ct.parts[stm]
+		if stm.readOnly {
+			t.version = stm.version // nothing to publish, the version of a replica follows its primary
+			continue
+		}
+		t.apply(newValues[i])
+	}
+	return nil, nil
+}
+
+// abortAttempt records the rollback of the current attempt in every part.
+func (ct *CrossTransaction) abortAttempt(reason AbortReason, err error) {
+	for _, stm := range ct.order {
+		stm.abortAttempt(ct.parts[stm], reason, err)
+	}
+}
+
+// rollback rolls every part back, so that the transaction can retry.
+func (ct *CrossTransaction) rollback() {
+	for _, t := range ct.parts {
+		t.rollback()
+	}
+}
+
+// awaitChange blocks until one of the memory cells read by the parts has been changed by
+// another transaction. Every part waits for the commits of its STM, the first part to
+// see a change wakes the others up. It gives back the error of the context of the
+// transaction when the context is done first.
+func (ct *CrossTransaction) awaitChange() error {
+	if len(ct.order) == 1 {
+		return ct.parts[ct.order[0]].awaitChange(ct.ctx)
+	}
+
+	ctx, changed := context.WithCancel(ct.ctx) // done once a part has seen a change
+	defer changed()
+	var wg sync.WaitGroup
+	for _, stm := range ct.order {
+		t := ct.parts[stm]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if t.awaitChange(ctx) == nil {
+				changed()
+			}
+		}()
+	}
+	wg.Wait() // the parts are rolled back once no longer read
+	return ct.ctx.Err()
+}
+
+// hasRead checks if any of the parts has read a memory cell.
+func (ct *CrossTransaction) hasRead() bool {
+	for _, t := range ct.parts {
+		if len(t.readQuarantine) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty checks if the transaction has neither read nor written anything.
+func (t *Transaction) isEmpty() bool {
+	return len(t.readQuarantine) == 0 && len(t.writeQuarantine) == 0 && len(t.commutes) == 0 && len(t.newCells) == 0
+}
@@ -0,0 +1,216 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// coordinator_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:35:58 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoAcrossConservesTheTotal(t *testing.T) {
+	const accounts, initial = 4, 1000
+	stms := make([]*STM, accounts)
+	balances := make([]TVar, accounts)
+	for i := range stms {
+		stms[i] = New()
+		balances[i] = stms[i].NewTVar(counterValue(initial))
+	}
+	total := func(ct *CrossTransaction) counterValue {
+		sum := counterValue(0)
+		for i, balance := range balances {
+			sum += ct.On(stms[i]).Read(balance).(counterValue)
+		}
+		return sum
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				from, to := rand.Intn(accounts), rand.Intn(accounts)
+				amount := counterValue(rand.Intn(10))
+				err := DoAcross(func(ct *CrossTransaction) bool {
+					src, dst := ct.On(stms[from]), ct.On(stms[to])
+					src.Write(balances[from], src.Read(balances[from]).(counterValue)-amount)
+					return dst.Write(balances[to], dst.Read(balances[to]).(counterValue)+amount)
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	for n := 0; n < 100; n++ {
+		DoAcross(func(ct *CrossTransaction) bool {
+			if sum := total(ct); sum != accounts*initial {
+				t.Errorf("total %d while transferring, want %d", sum, accounts*initial)
+			}
+			return true
+		})
+	}
+	wg.Wait()
+
+	DoAcross(func(ct *CrossTransaction) bool {
+		if sum := total(ct); sum != accounts*initial {
+			t.Errorf("total %d after the transfers, want %d", sum, accounts*initial)
+		}
+		return true
+	})
+}
+
+func TestDoAcrossTakesBackThePartsLogged(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Open(filepath.Join(dir, "first.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Open(filepath.Join(dir, "second.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := first.NewNamedTVar("x", counterValue(0))
+	z := first.NewNamedTVar("z", counterValue(0))
+	y := second.NewNamedTVar("y", counterValue(0))
+	second.Close() // the part of the second STM can't be logged, it is logged last
+
+	err = DoAcross(func(ct *CrossTransaction) bool {
+		ct.On(first).Write(x, counterValue(1))
+		return ct.On(second).Write(y, counterValue(1))
+	})
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("DoAcross returned %v, want ErrClosed", err)
+	}
+	if err := first.Do(func(t *Transaction) bool { return t.Write(z, counterValue(2)) }); err != nil {
+		t.Fatalf("commit after the aborted transaction: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err = Open(filepath.Join(dir, "first.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if got := first.NewNamedTVar("x", counterValue(0)).(*memoryCell).read(); got != counterValue(0) {
+		t.Errorf("recovered x = %v, want 0 -- the aborted part was recovered", got)
+	}
+	if got := first.NewNamedTVar("z", counterValue(0)).(*memoryCell).read(); got != counterValue(2) {
+		t.Errorf("recovered z = %v, want 2", got)
+	}
+}
+
+func TestDoAcrossRetryWithoutReads(t *testing.T) {
+	s := New()
+	err := DoAcross(func(ct *CrossTransaction) bool {
+		ct.On(s).Retry()
+		return true
+	})
+	if !errors.Is(err, ErrRetryWithoutReads) {
+		t.Errorf("DoAcross returned %v, want ErrRetryWithoutReads", err)
+	}
+}
+
+func TestDoAcrossRetryWaitsForEitherSTM(t *testing.T) {
+	s1, s2 := New(), New()
+	a, b := s1.NewTVar(counterValue(0)), s2.NewTVar(counterValue(0))
+
+	for _, changed := range []struct {
+		s    *STM
+		tVar TVar
+	}{{s1, a}, {s2, b}} {
+		done := make(chan error, 1)
+		go func() {
+			done <- DoAcross(func(ct *CrossTransaction) bool {
+				if ct.On(s1).Read(a) == counterValue(0) && ct.On(s2).Read(b) == counterValue(0) {
+					ct.On(s1).Retry()
+				}
+				return true
+			})
+		}()
+		select {
+		case err := <-done:
+			t.Fatalf("DoAcross returned %v before anything changed", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		set(changed.s, changed.tVar, counterValue(1))
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("DoAcross wasn't woken up by the change")
+		}
+		set(changed.s, changed.tVar, counterValue(0))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := DoAcrossContext(ctx, func(ct *CrossTransaction) bool {
+		ct.On(s1).Read(a)
+		ct.On(s2).Read(b)
+		ct.On(s1).Retry()
+		return true
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DoAcrossContext returned %v, want the error of the context", err)
+	}
+}
+
+func TestTVarOfAnotherSTMPanics(t *testing.T) {
+	s1, s2 := New(), New()
+	foreign := s2.NewTVar(counterValue(0))
+	s1.Do(func(tx *Transaction) bool {
+		expectPanic(t, "Read", func() { tx.Read(foreign) })
+		expectPanic(t, "Write", func() { tx.Write(foreign, counterValue(1)) })
+		expectPanic(t, "Commute", func() { tx.Commute(foreign, func(v Value) Value { return v }) })
+		return true
+	})
+	if countOf(foreign) != 0 {
+		t.Error("the memory cell of another STM was written")
+	}
+}
@@ -0,0 +1,79 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// counter.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:39:21 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:39:21 GMT-0700 (PDT)
+//
+
+package stm
+
+// TCounter is a transactional counter. Additions to the counter commute, so transactions
+// concurrently adding to the same counter don't conflict with each other, see
+// Transaction.Commute. Reading the counter is a regular serializable read.
+type TCounter struct {
+	cell TVar // the memory cell holding the count
+}
+
+// counterValue is the count of a TCounter. It is stored in a memory cell.
+type counterValue int64
+
+// MakeCopy makes counterValue conform to the Value interface.
+func (c counterValue) MakeCopy() Value {
+	return c
+}
+
+// IsEqual checks the equality between two counts.
+func (c counterValue) IsEqual(v Value) bool {
+	vv, ok := v.(counterValue)
+	return ok && vv == c
+}
+
+// NewTCounter creates a new counter managed by the STM, starting at the `initial` count.
+func NewTCounter(stm *STM, initial int64) *TCounter {
+	c := new(TCounter)
+	c.cell = stm.NewTVar(counterValue(initial))
+	return c
+}
+
+// Add adds the `delta` to the counter. The addition is applied when the transaction commits.
+func (c *TCounter) Add(t *Transaction, delta int64) bool {
+	return t.Commute(c.cell, func(v Value) Value {
+		return v.(counterValue) + counterValue(delta)
+	})
+}
+
+// Get gives the current count, including the additions made by the transaction.
+// The transaction conflicts with the concurrent additions once it has read the count.
+func (c *TCounter) Get(t *Transaction) int64 {
+	return int64(t.Read(c.cell).(counterValue))
+}
@@ -0,0 +1,145 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// counter_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:57:50 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:57:50 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTCounterConcurrentAddsDontConflict(t *testing.T) {
+	s := New()
+	c := NewTCounter(s, 10)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				s.Do(func(t *Transaction) bool { return c.Add(t, 1) })
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.Do(func(tx *Transaction) bool {
+		if got := c.Get(tx); got != 810 {
+			t.Errorf("Get: got %d, want 810", got)
+		}
+		return true
+	})
+	if conflicts := s.Stats().Aborts[AbortConflict]; conflicts != 0 {
+		t.Errorf("%d conflicts between transactions only adding to the counter, want none", conflicts)
+	}
+}
+
+func TestTCounterGetSeesTheAdditions(t *testing.T) {
+	s := New()
+	c := NewTCounter(s, 1)
+
+	s.Do(func(tx *Transaction) bool {
+		c.Add(tx, 2)
+		c.Add(tx, 3)
+		if got := c.Get(tx); got != 6 {
+			t.Errorf("Get after the additions: got %d, want 6", got)
+		}
+		c.Add(tx, 4) // commuted after the read, it is still applied at commit
+		if got := c.Get(tx); got != 10 {
+			t.Errorf("Get after another addition: got %d, want 10", got)
+		}
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		if got := c.Get(tx); got != 10 {
+			t.Errorf("Get after the commit: got %d, want 10", got)
+		}
+		return true
+	})
+}
+
+func TestTCounterGetConflictsWithAdditions(t *testing.T) {
+	s := New()
+	c := NewTCounter(s, 0)
+
+	attempts := 0
+	s.Do(func(tx *Transaction) bool {
+		attempts++
+		count := c.Get(tx)
+		if attempts == 1 {
+			// a concurrent addition commits after the read
+			s.Do(func(t *Transaction) bool { return c.Add(t, 1) })
+		}
+		return c.Add(tx, count) // doubles the count
+	})
+
+	if attempts != 2 {
+		t.Errorf("%d attempts, want the read to conflict with the addition once", attempts)
+	}
+	s.Do(func(tx *Transaction) bool {
+		if got := c.Get(tx); got != 2 {
+			t.Errorf("Get: got %d, want 2", got)
+		}
+		return true
+	})
+}
+
+func TestCommuteAfterWrite(t *testing.T) {
+	s := New()
+	c := NewTCounter(s, 0)
+
+	s.Do(func(tx *Transaction) bool {
+		tx.Write(c.cell, counterValue(5))
+		c.Add(tx, 1) // applied to the written value right away
+		if got := c.Get(tx); got != 6 {
+			t.Errorf("Get after a write and an addition: got %d, want 6", got)
+		}
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		c.Add(tx, 1)
+		tx.Write(c.cell, counterValue(5)) // the write overrides the addition
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		if got := c.Get(tx); got != 5 {
+			t.Errorf("Get after an addition and a write: got %d, want 5", got)
+		}
+		return true
+	})
+}
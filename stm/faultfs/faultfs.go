@@ -0,0 +1,339 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// faultfs.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:56:26 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:28:41 GMT-0700 (PDT)
+//
+
+// Package faultfs is a fault-injecting stm.FileSystem for exercising the crash safety
+// of a persisted STM. It simulates the process or the machine crashing at arbitrary
+// points: a crash tears the write in progress, loses everything that wasn't synced
+// and fails every operation after it. It also fails single operations on demand,
+// without crashing, like a full or failing disk does.
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+var (
+	// ErrCrashed is the error of every operation after the crash.
+	ErrCrashed = errors.New("faultfs: crashed")
+
+	// ErrInjected is the error of the operations failed on demand, see FailNext.
+	ErrInjected = errors.New("faultfs: injected failure")
+)
+
+// Op is an operation a failure can be injected into, see FailNext.
+type Op int
+
+const (
+	// OpWrite is File.Write. The failed write is torn, half of its bytes are written.
+	OpWrite Op = iota
+
+	// OpSync is File.Sync. The failed sync flushes nothing, the bytes it was to flush
+	// are lost by a crash.
+	OpSync
+
+	// OpRename is FS.Rename. The failed rename leaves the files as they were.
+	OpRename
+)
+
+// FS is a stm.FileSystem that crashes on demand. It wraps another stm.FileSystem,
+// usually stm.OSFileSystem, and keeps track of how much of every file it opened
+// has been synced.
+type FS struct {
+	fs      stm.FileSystem // the wrapped file system
+	lock    *sync.Mutex    // guards the state of the file system
+	budget  int64          // the bytes that can be written before the crash, unlimited when negative
+	crashed bool           // the file system has crashed
+	failing map[Op]int     // the number of the next operations of every kind to fail
+	files   []*file        // the files opened so far
+}
+
+// file is a file opened by FS.
+type file struct {
+	fs     *FS      // the file system that opened the file
+	file   stm.File // the wrapped file
+	offset int64    // the offset of the next read or write
+	size   int64    // the size of the file
+	synced int64    // the size of the file that survives a crash
+}
+
+// New makes a new FS wrapping the `fs`. It doesn't crash until it is told to.
+func New(fs stm.FileSystem) *FS {
+	f := new(FS)
+	f.fs = fs
+	f.lock = new(sync.Mutex)
+	f.budget = -1
+	f.failing = make(map[Op]int)
+	return f
+}
+
+// CrashAfter makes the file system crash once `n` more bytes have been written. The
+// write crossing the limit is torn, only the bytes within the limit are written. A
+// negative `n` lifts the limit.
+func (f *FS) CrashAfter(n int64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.budget = n
+}
+
+// FailNext makes the next operation `op` fail with ErrInjected, without crashing. Every
+// call fails one more of the operations.
+func (f *FS) FailNext(op Op) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failing[op]++
+}
+
+// fails checks if the operation `op` is to fail, and counts the failure. The caller must
+// hold the lock.
+func (f *FS) fails(op Op) bool {
+	if f.failing[op] == 0 {
+		return false
+	}
+	f.failing[op]--
+	return true
+}
+
+// Crash crashes the file system now.
+func (f *FS) Crash() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.crash()
+}
+
+// Crashed checks if the file system has crashed.
+func (f *FS) Crashed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.crashed
+}
+
+// crash cuts every file opened so far back to the size that was synced and closes it.
+// The caller must hold the lock.
+func (f *FS) crash() {
+	if f.crashed {
+		return
+	}
+	f.crashed = true
+	for _, fl := range f.files {
+		fl.file.Truncate(fl.synced)
+		fl.file.Close()
+	}
+	f.files = nil
+}
+
+// OpenFile makes FS conform to the stm.FileSystem interface.
+func (f *FS) OpenFile(name string, flag int, perm os.FileMode) (stm.File, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.crashed {
+		return nil, ErrCrashed
+	}
+	inner, err := f.fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	size, err := inner.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = inner.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+
+	fl := new(file)
+	fl.fs = f
+	fl.file = inner
+	fl.size = size
+	fl.synced = size // what is on the disk already survives a crash
+	f.files = append(f.files, fl)
+	return fl, nil
+}
+
+// Rename makes FS conform to the stm.FileSystem interface. A rename is durable at once.
+func (f *FS) Rename(oldpath, newpath string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.crashed {
+		return ErrCrashed
+	}
+	if f.fails(OpRename) {
+		return ErrInjected
+	}
+	return f.fs.Rename(oldpath, newpath)
+}
+
+// Remove makes FS conform to the stm.FileSystem interface. A removal is durable at once.
+func (f *FS) Remove(name string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.crashed {
+		return ErrCrashed
+	}
+	return f.fs.Remove(name)
+}
+
+// ------------------------------------------------------------------------
+
+// Read makes file conform to the stm.File interface.
+func (fl *file) Read(p []byte) (int, error) {
+	fl.fs.lock.Lock()
+	defer fl.fs.lock.Unlock()
+
+	if fl.fs.crashed {
+		return 0, ErrCrashed
+	}
+	n, err := fl.file.Read(p)
+	fl.offset += int64(n)
+	return n, err
+}
+
+// Write makes file conform to the stm.File interface. It crashes the file system when
+// the write goes over the budget of the file system.
+func (fl *file) Write(p []byte) (int, error) {
+	fl.fs.lock.Lock()
+	defer fl.fs.lock.Unlock()
+
+	if fl.fs.crashed {
+		return 0, ErrCrashed
+	}
+
+	failed := fl.fs.fails(OpWrite)
+	if failed {
+		p = p[:len(p)/2]
+	}
+	torn := false
+	if budget := fl.fs.budget; budget >= 0 && int64(len(p)) > budget {
+		p, torn = p[:budget], true
+	}
+	n, err := fl.file.Write(p)
+	fl.offset += int64(n)
+	if fl.offset > fl.size {
+		fl.size = fl.offset
+	}
+	if fl.fs.budget >= 0 {
+		fl.fs.budget -= int64(n)
+	}
+
+	if torn {
+		// the torn bytes made it to the disk, as if the page was flushed before the crash
+		fl.synced = fl.size
+		fl.fs.crash()
+		return n, ErrCrashed
+	}
+	if failed && err == nil {
+		err = ErrInjected
+	}
+	return n, err
+}
+
+// Seek makes file conform to the stm.File interface.
+func (fl *file) Seek(offset int64, whence int) (int64, error) {
+	fl.fs.lock.Lock()
+	defer fl.fs.lock.Unlock()
+
+	if fl.fs.crashed {
+		return 0, ErrCrashed
+	}
+	off, err := fl.file.Seek(offset, whence)
+	if err == nil {
+		fl.offset = off
+	}
+	return off, err
+}
+
+// Sync makes file conform to the stm.File interface.
+func (fl *file) Sync() error {
+	fl.fs.lock.Lock()
+	defer fl.fs.lock.Unlock()
+
+	if fl.fs.crashed {
+		return ErrCrashed
+	}
+	if fl.fs.fails(OpSync) {
+		return ErrInjected
+	}
+	if err := fl.file.Sync(); err != nil {
+		return err
+	}
+	fl.synced = fl.size
+	return nil
+}
+
+// Truncate makes file conform to the stm.File interface. A truncation is durable at once.
+func (fl *file) Truncate(size int64) error {
+	fl.fs.lock.Lock()
+	defer fl.fs.lock.Unlock()
+
+	if fl.fs.crashed {
+		return ErrCrashed
+	}
+	if err := fl.file.Truncate(size); err != nil {
+		return err
+	}
+	fl.size = size
+	if fl.synced > size {
+		fl.synced = size
+	}
+	return nil
+}
+
+// Close makes file conform to the stm.File interface. The file is no longer cut back
+// by a crash, what was written to it is taken to be on the disk.
+func (fl *file) Close() error {
+	fl.fs.lock.Lock()
+	defer fl.fs.lock.Unlock()
+
+	if fl.fs.crashed {
+		return ErrCrashed
+	}
+	for i, other := range fl.fs.files {
+		if other == fl {
+			fl.fs.files = append(fl.fs.files[:i], fl.fs.files[i+1:]...)
+			break
+		}
+	}
+	return fl.file.Close()
+}
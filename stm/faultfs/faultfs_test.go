@@ -0,0 +1,205 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// faultfs_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:28:41 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:29:44 GMT-0700 (PDT)
+//
+
+package faultfs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// amount is the value of the memory cell persisted by the tests.
+type amount int
+
+// MakeCopy makes amount conform to the stm.Value interface.
+func (a amount) MakeCopy() stm.Value {
+	return a
+}
+
+// IsEqual makes amount conform to the stm.Value interface.
+func (a amount) IsEqual(v stm.Value) bool {
+	other, ok := v.(amount)
+	return ok && other == a
+}
+
+// codecs encodes the amounts.
+var codecs = stm.NewCodecRegistry()
+
+func init() {
+	codecs.Register("faultfs.amount", stm.JSONEncoding[amount]())
+}
+
+// open opens the persisted STM at the `path` on the file system, and its balance.
+func open(t *testing.T, path string, fs stm.FileSystem) (*stm.STM, stm.TVar) {
+	t.Helper()
+	s, err := stm.Open(path, stm.WithFileSystem(fs), stm.WithCodec(codecs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, s.NewNamedTVar("balance", amount(0))
+}
+
+// set sets the balance.
+func set(s *stm.STM, balance stm.TVar, a amount) error {
+	return s.Do(func(t *stm.Transaction) bool {
+		return t.Write(balance, a)
+	})
+}
+
+// recovered opens the persisted STM at the `path` again, after the crash, and gives the
+// balance it recovered.
+func recovered(t *testing.T, path string) amount {
+	t.Helper()
+	s, balance := open(t, path, stm.OSFileSystem)
+	defer s.Close()
+	var a amount
+	s.Do(func(t *stm.Transaction) bool {
+		a = t.Read(balance).(amount)
+		return true
+	})
+	return a
+}
+
+func TestCrashDuringAppend(t *testing.T) {
+	for n := int64(0); ; n++ {
+		path := filepath.Join(t.TempDir(), "stm.wal")
+		fs := New(stm.OSFileSystem)
+		s, balance := open(t, path, fs)
+		if err := set(s, balance, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		fs.CrashAfter(n)
+		err := set(s, balance, 2)
+		want := amount(2)
+		if err != nil {
+			want = 1
+		}
+		fs.Crash()
+		if got := recovered(t, path); got != want {
+			t.Fatalf("crash after %d bytes (commit error %v): recovered %d, want %d", n, err, got, want)
+		}
+		if err == nil {
+			return // the whole record was written
+		}
+	}
+}
+
+func TestFailedAppendIsNotRecovered(t *testing.T) {
+	for _, op := range []Op{OpWrite, OpSync} {
+		for _, crash := range []bool{false, true} {
+			path := filepath.Join(t.TempDir(), "stm.wal")
+			fs := New(stm.OSFileSystem)
+			s, balance := open(t, path, fs)
+			if err := set(s, balance, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			fs.FailNext(op)
+			if err := set(s, balance, 2); err == nil {
+				t.Errorf("op %d: the commit succeeded despite the failure", op)
+			}
+			if crash {
+				fs.Crash()
+			} else {
+				s.Close() // the aborted commit made it to the disk unless it was taken back
+			}
+			if got := recovered(t, path); got != 1 {
+				t.Errorf("op %d, crash %v: recovered %d, want 1", op, crash, got)
+			}
+		}
+	}
+}
+
+func TestFailedCheckpointKeepsTheLastDurableState(t *testing.T) {
+	inject := map[string]func(*FS){
+		"write":  func(fs *FS) { fs.FailNext(OpWrite) },
+		"sync":   func(fs *FS) { fs.FailNext(OpSync) },
+		"rename": func(fs *FS) { fs.FailNext(OpRename) },
+	}
+	for name, fail := range inject {
+		path := filepath.Join(t.TempDir(), "stm.wal")
+		fs := New(stm.OSFileSystem)
+		s, balance := open(t, path, fs)
+		if err := set(s, balance, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		fail(fs)
+		if err := s.Checkpoint(); err == nil {
+			t.Errorf("%s: the checkpoint succeeded despite the failure", name)
+		}
+		if err := set(s, balance, 2); err != nil {
+			t.Errorf("%s: commit after the failed checkpoint: %v", name, err)
+		}
+		fs.Crash()
+		if got := recovered(t, path); got != 2 {
+			t.Errorf("%s: recovered %d, want 2", name, got)
+		}
+	}
+}
+
+func TestCrashDuringCheckpoint(t *testing.T) {
+	for n := int64(0); ; n++ {
+		path := filepath.Join(t.TempDir(), "stm.wal")
+		fs := New(stm.OSFileSystem)
+		s, balance := open(t, path, fs)
+		if err := set(s, balance, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		fs.CrashAfter(n)
+		err := s.Checkpoint()
+		want := amount(1)
+		if !fs.Crashed() {
+			fs.CrashAfter(-1)
+			if err := set(s, balance, 2); err != nil {
+				t.Fatalf("crash after %d bytes: commit after the checkpoint: %v", n, err)
+			}
+			want = 2
+		}
+		fs.Crash()
+		if got := recovered(t, path); got != want {
+			t.Fatalf("crash after %d bytes (checkpoint error %v): recovered %d, want %d", n, err, got, want)
+		}
+		if err == nil {
+			return // the whole checkpoint was written
+		}
+	}
+}
@@ -0,0 +1,103 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// filesystem.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:56:26 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:56:26 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem is the file system a persisted STM keeps its write-ahead log and checkpoints
+// on, see WithFileSystem. It is the operating system's file system by default.
+type FileSystem interface {
+	// OpenFile opens the named file like os.OpenFile.
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+
+	// Rename renames the file atomically like os.Rename.
+	Rename(oldpath, newpath string) error
+
+	// Remove removes the named file like os.Remove.
+	Remove(name string) error
+}
+
+// File is a file opened by a FileSystem.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+
+	// Sync commits the contents of the file to stable storage.
+	Sync() error
+
+	// Truncate changes the size of the file.
+	Truncate(size int64) error
+}
+
+// OSFileSystem is the FileSystem of the operating system.
+var OSFileSystem FileSystem = osFileSystem{}
+
+// osFileSystem is the FileSystem backed by the os package.
+type osFileSystem struct{}
+
+// OpenFile makes osFileSystem conform to the FileSystem interface.
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+// Rename makes osFileSystem conform to the FileSystem interface.
+func (osFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Remove makes osFileSystem conform to the FileSystem interface.
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// syncDir commits the entries of the directory of the `path` to stable storage, making
+// a rename in it durable. Not every file system can sync a directory, a failure is ignored.
+func syncDir(fs FileSystem, path string) {
+	dir, err := fs.OpenFile(filepath.Dir(path), os.O_RDONLY, 0)
+	if err != nil {
+		return
+	}
+	dir.Sync()
+	dir.Close()
+}
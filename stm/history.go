@@ -0,0 +1,249 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// history.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:59:52 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:29:15 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionNotRetained is the reason a time-travel read is refused, the version it
+// reads is older than the versions the STM retains, see STM.SetRetention.
+var ErrVersionNotRetained = errors.New("stm: the version is not retained")
+
+// cellVersion is the contents of a memory cell as of a version of the STM.
+type cellVersion struct {
+	version uint64 // the version of the STM the contents were written at
+	value   Value  // the contents, never modified once written
+}
+
+// readAtSignal is raised by a read-only transaction reading a memory cell that has no
+// contents as of the version it reads, to abandon the transaction.
+type readAtSignal struct {
+	memCell *memoryCell // the memory cell read
+}
+
+// SetRetention sets the number of past versions of the STM that are retained for
+// ReadAt and RestoreTo. Retaining versions keeps the past contents of the memory cells
+// written within the retention window. No versions are retained by default, only the
+// current version can be read then. The versions from the call on are retained.
+func (stm *STM) SetRetention(versions uint64) {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if versions > 0 && stm.retention == 0 {
+		stm.retainedFrom = stm.version
+	}
+	stm.retention = versions
+}
+
+// oldestVersion gives the oldest version of the STM that can be read. The caller
+// must hold the commit lock.
+func (stm *STM) oldestVersion() uint64 {
+	if stm.retention == 0 {
+		return stm.version
+	}
+	oldest := stm.retainedFrom
+	if stm.version > stm.retention && stm.version-stm.retention > oldest {
+		oldest = stm.version - stm.retention
+	}
+	return oldest
+}
+
+// historyFloor gives the oldest version the past contents of the memory cells are kept
+// for, the oldest version that can be read or the oldest version being read by ReadAt.
+// The caller must hold the commit lock.
+func (stm *STM) historyFloor() uint64 {
+	floor := stm.oldestVersion()
+	for version := range stm.pins {
+		if version < floor {
+			floor = version
+		}
+	}
+	return floor
+}
+
+// ReadAt runs `fn` in a read-only transaction reading the STM as of the `version`, a
+// past commit version within the retention window or the current version, see
+// SetRetention and Transaction.Version. The writes of the transaction are refused.
+// The version is retained while `fn` runs. It returns ErrVersionNotRetained when the
+// version is too old or `fn` reads a memory cell created after the version.
+func (stm *STM) ReadAt(version uint64, fn func(*Transaction)) (err error) {
+	if err := stm.pin(version); err != nil {
+		return err
+	}
+	defer stm.unpin(version)
+
+	t := newTransaction(stm, nil)
+	t.readOnly = true
+	t.version = version
+
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case readAtSignal:
+			err = fmt.Errorf("%w: memory cell %s at %d", ErrVersionNotRetained, r.memCell.label(), version)
+		case retrySignal:
+			err = errors.New("stm: Retry in a read-only transaction")
+		default:
+			panic(r)
+		}
+	}()
+
+	fn(t)
+	return nil
+}
+
+// pin keeps the contents of the memory cells as of the `version` until unpin is called.
+// It fails when the version isn't committed yet or isn't retained anymore.
+func (stm *STM) pin(version uint64) error {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if version > stm.version {
+		return fmt.Errorf("stm: version %d is not committed yet", version)
+	}
+	if version < stm.oldestVersion() {
+		return fmt.Errorf("%w: %d", ErrVersionNotRetained, version)
+	}
+	stm.pins[version]++
+	return nil
+}
+
+// unpin releases the contents of the memory cells kept by pin.
+func (stm *STM) unpin(version uint64) {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if stm.pins[version]--; stm.pins[version] == 0 {
+		delete(stm.pins, version)
+	}
+}
+
+// RestoreTo rolls all the memory cells of the STM back to their contents as of the
+// `version`, a past commit version within the retention window. The roll back is a
+// transaction labelled "restore" that commits a new version, so it is validated,
+// persisted and watched like any other and the versions before it can still be read.
+// The contents as of the version are read and written back in that one transaction.
+// The memory cells created after the version are left as they are.
+func (stm *STM) RestoreTo(version uint64) error {
+	if err := stm.pin(version); err != nil {
+		return err
+	}
+	defer stm.unpin(version)
+
+	// the contents as of the version are read by the restoring transaction itself, it
+	// reads every memory cell it restores so a commit in between makes it start over
+	return stm.DoLabelled("restore", func(t *Transaction) bool {
+		stm.memoryLock.RLock()
+		memCells := append([]*memoryCell(nil), stm.memory...)
+		stm.memoryLock.RUnlock()
+
+		for _, memCell := range memCells {
+			if memCell.compute != nil {
+				continue // computed TVars follow the memory cells they are computed from
+			}
+			if value, ok := memCell.readAt(version); ok && !t.Read(memCell).IsEqual(value) {
+				t.Write(memCell, value)
+			}
+		}
+		return true
+	})
+}
+
+// readAt reads the memory cell as of the version of the read-only transaction.
+func (t *Transaction) readAt(memCell *memoryCell) Value {
+	if memCell.compute != nil {
+		return memCell.compute(t) // computed from the memory cells as of the version
+	}
+	value, ok := memCell.readAt(t.version)
+	if !ok {
+		panic(readAtSignal{memCell})
+	}
+	return value
+}
+
+// readAt reads a copy of the contents of the memory cell as of the `version`. It returns
+// false when the memory cell has no contents as of the version, it was created after
+// the version or its contents as of the version are no longer kept.
+func (memCell *memoryCell) readAt(version uint64) (Value, bool) {
+	memCell.memCellLock.RLock()
+	defer memCell.memCellLock.RUnlock()
+
+	if len(memCell.history) == 0 {
+		if memCell.lastVersion <= version {
+			return memCell.data.MakeCopy(), true
+		}
+		return nil, false
+	}
+	for i := len(memCell.history) - 1; i >= 0; i-- {
+		if memCell.history[i].version <= version {
+			return memCell.history[i].value.MakeCopy(), true
+		}
+	}
+	return nil, false
+}
+
+// writeVersion writes the new contents of the memory cell at the `version` of the STM.
+// The past contents are kept as far back as needed to read the memory cell as of the
+// `floor` version.
+func (memCell *memoryCell) writeVersion(value Value, version, floor uint64) {
+	memCell.memCellLock.Lock()
+	defer memCell.memCellLock.Unlock()
+
+	if floor < version {
+		if len(memCell.history) == 0 {
+			memCell.history = append(memCell.history, cellVersion{memCell.lastVersion, memCell.data})
+		}
+		memCell.history = append(memCell.history, cellVersion{version, value})
+
+		// the contents before the last contents written at or before the floor aren't needed
+		i := 0
+		for i+1 < len(memCell.history) && memCell.history[i+1].version <= floor {
+			i++
+		}
+		if i > 0 {
+			memCell.history = append(memCell.history[:0], memCell.history[i:]...)
+		}
+	} else {
+		memCell.history = nil
+	}
+
+	memCell.data = value
+	memCell.lastVersion = version
+}
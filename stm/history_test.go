@@ -0,0 +1,192 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// history_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:29:15 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:29:15 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestReadAt(t *testing.T) {
+	s := New()
+	s.SetRetention(10)
+	a := s.NewTVar(counterValue(1))
+	before := s.Version()
+	set(s, a, counterValue(2))
+	after := s.Version()
+	b := s.NewTVar(counterValue(0))
+
+	err := s.ReadAt(before, func(t2 *Transaction) {
+		if t2.Version() != before || t2.Read(a) != counterValue(1) {
+			t.Errorf("read %v as of %d, want 1 as of %d", t2.Read(a), t2.Version(), before)
+		}
+		if t2.Write(a, counterValue(3)) {
+			t.Error("a read-only transaction wrote a memory cell")
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ReadAt(after, func(t2 *Transaction) {
+		if t2.Read(a) != counterValue(2) {
+			t.Errorf("read %v as of %d, want 2", t2.Read(a), after)
+		}
+	})
+	if countOf(a) != 2 {
+		t.Errorf("the memory cell holds %v, want 2", countOf(a))
+	}
+
+	if err := s.ReadAt(before, func(t *Transaction) { t.Read(b) }); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("reading a memory cell created after the version: got %v, want ErrVersionNotRetained", err)
+	}
+	if err := s.ReadAt(s.Version()+1, func(*Transaction) {}); err == nil || errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("reading a version not committed yet: got %v", err)
+	}
+}
+
+func TestReadsPastTheRetention(t *testing.T) {
+	s := New()
+	a := s.NewTVar(counterValue(0))
+	set(s, a, counterValue(1))
+	if err := s.ReadAt(s.Version()-1, func(*Transaction) {}); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("read a past version with no retention: got %v", err)
+	}
+	if err := s.ReadAt(s.Version(), func(*Transaction) {}); err != nil {
+		t.Errorf("the current version can't be read: %v", err)
+	}
+
+	s.SetRetention(2)
+	from := s.Version()
+	if err := s.ReadAt(from-1, func(*Transaction) {}); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("read a version older than the SetRetention call: got %v", err)
+	}
+	for i := 2; i <= 4; i++ {
+		set(s, a, counterValue(i))
+	}
+	if err := s.ReadAt(from, func(*Transaction) {}); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("read a version past the retention window: got %v", err)
+	}
+	s.ReadAt(s.Version()-2, func(t2 *Transaction) {
+		if t2.Read(a) != counterValue(2) {
+			t.Errorf("read %v at the end of the retention window, want 2", t2.Read(a))
+		}
+	})
+
+	// the version being read is kept while it is read, past the retention window
+	pinned := s.Version()
+	s.ReadAt(pinned, func(t2 *Transaction) {
+		for i := 5; i <= 8; i++ {
+			set(s, a, counterValue(i))
+		}
+		if t2.Read(a) != counterValue(4) {
+			t.Errorf("read %v as of %d, want 4", t2.Read(a), pinned)
+		}
+	})
+	if err := s.ReadAt(pinned, func(*Transaction) {}); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("the version is still retained once read: got %v", err)
+	}
+}
+
+func TestRestoreTo(t *testing.T) {
+	s := New()
+	s.SetRetention(10)
+	a, b := s.NewTVar(counterValue(1)), s.NewTVar(counterValue(2))
+	version := s.Version()
+	set(s, a, counterValue(5))
+	set(s, b, counterValue(7))
+	created := s.NewTVar(counterValue(9))
+	restored := s.Version()
+
+	if err := s.RestoreTo(version); err != nil {
+		t.Fatal(err)
+	}
+	if countOf(a) != 1 || countOf(b) != 2 || countOf(created) != 9 {
+		t.Errorf("restored %v, %v and %v, want 1, 2 and 9", countOf(a), countOf(b), countOf(created))
+	}
+	if s.Version() != restored+1 {
+		t.Errorf("the restore committed version %d, want %d", s.Version(), restored+1)
+	}
+	s.ReadAt(restored, func(t2 *Transaction) {
+		if t2.Read(a) != counterValue(5) {
+			t.Errorf("read %v as of the version before the restore, want 5", t2.Read(a))
+		}
+	})
+
+	for i := 0; i < 10; i++ {
+		set(s, a, counterValue(i))
+	}
+	if err := s.RestoreTo(version); !errors.Is(err, ErrVersionNotRetained) {
+		t.Errorf("restored a version past the retention window: got %v", err)
+	}
+}
+
+func TestRestoreToUnderConcurrentCommits(t *testing.T) {
+	s := New()
+	s.SetRetention(1000)
+	a, b := s.NewTVar(counterValue(5)), s.NewTVar(counterValue(5))
+	s.AddInvariant("total is 10", []TVar{a, b}, func(values []Value) error {
+		if values[0].(counterValue)+values[1].(counterValue) != 10 {
+			return errors.New("unbalanced")
+		}
+		return nil
+	})
+	version := s.Version()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.Do(func(t *Transaction) bool {
+				t.Write(a, t.Read(a).(counterValue)-1)
+				return t.Write(b, t.Read(b).(counterValue)+1)
+			})
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if err := s.RestoreTo(version); err != nil {
+			t.Errorf("restore: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if err := s.RestoreTo(version); err != nil || countOf(a) != 5 || countOf(b) != 5 {
+		t.Errorf("restored %v and %v (error %v), want 5 and 5", countOf(a), countOf(b), err)
+	}
+}
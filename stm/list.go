@@ -0,0 +1,302 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// list.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:40:25 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:59:38 GMT-0700 (PDT)
+//
+
+package stm
+
+// TList is a transactional doubly linked list. Every node of the list is its own memory
+// cell, so transactions working at different positions of the list don't conflict.
+// The list is circular around a sentinel root node.
+type TList struct {
+	root TVar // the sentinel node, its next is the front and its prev is the back of the list
+}
+
+// TListElement is a reference to an element of a TList.
+type TListElement struct {
+	node TVar // the node of the element
+}
+
+// TListCursor walks a TList forwards or backwards. It is only valid within the
+// transaction it was made in.
+type TListCursor struct {
+	list *TList       // the list being walked
+	t    *Transaction // the transaction the cursor is valid in
+	at   TVar         // the node the cursor is at, the root when it is off the list
+}
+
+// listNode is a node of the list. It is stored in a memory cell.
+type listNode struct {
+	value Value // the value of the element, nil for the root
+	prev  TVar  // the previous node, nil once the node is removed
+	next  TVar  // the next node, nil once the node is removed
+}
+
+// MakeCopy makes listNode conform to the Value interface.
+func (n *listNode) MakeCopy() Value {
+	nn := new(listNode)
+	if n.value != nil {
+		nn.value = n.value.MakeCopy()
+	}
+	nn.prev = n.prev
+	nn.next = n.next
+	return nn
+}
+
+// IsEqual checks the equality between two nodes.
+func (n *listNode) IsEqual(v Value) bool {
+	vv, ok := v.(*listNode)
+	if !ok {
+		return false
+	}
+	if vv.prev != n.prev || vv.next != n.next {
+		return false
+	}
+	if vv.value == nil || n.value == nil {
+		return vv.value == nil && n.value == nil
+	}
+	return vv.value.IsEqual(n.value)
+}
+
+// NewTList creates a new empty list managed by the STM.
+func NewTList(stm *STM) *TList {
+	l := new(TList)
+	l.root = stm.newTVar("", func(self TVar) Value {
+		root := new(listNode)
+		root.prev = self // the empty list is the root linked to itself
+		root.next = self
+		return root
+	})
+	return l
+}
+
+// readNode reads the node referenced by the `tVar` in the transaction.
+func (l *TList) readNode(t *Transaction, tVar TVar) *listNode {
+	return t.Read(tVar).(*listNode)
+}
+
+// element gives the element for the `node`, nil when the node is the root.
+func (l *TList) element(node TVar) *TListElement {
+	if node == l.root {
+		return nil
+	}
+	e := new(TListElement)
+	e.node = node
+	return e
+}
+
+// link links the `node` into the list right after the node `at`.
+func (l *TList) link(t *Transaction, node TVar, n *listNode, at TVar) {
+	atn := l.readNode(t, at)
+	n.prev = at
+	n.next = atn.next
+	t.Write(node, n)
+
+	atn.next = node
+	t.Write(at, atn)
+
+	nextn := l.readNode(t, n.next) // the node at itself when the list was empty
+	nextn.prev = node
+	t.Write(n.next, nextn)
+}
+
+// unlink unlinks the `node` from the list. It returns false when the node was
+// already removed.
+func (l *TList) unlink(t *Transaction, node TVar) (*listNode, bool) {
+	n := l.readNode(t, node)
+	if n.next == nil {
+		return n, false
+	}
+
+	prevn := l.readNode(t, n.prev)
+	prevn.next = n.next
+	t.Write(n.prev, prevn)
+
+	nextn := l.readNode(t, n.next) // the same node as prev when it was the only element
+	nextn.prev = n.prev
+	t.Write(n.next, nextn)
+
+	n.prev = nil
+	n.next = nil
+	t.Write(node, n)
+	return n, true
+}
+
+// insertAfter inserts the `value` in a new node right after the node `at`.
+func (l *TList) insertAfter(t *Transaction, value Value, at TVar) *TListElement {
+	n := new(listNode)
+	n.value = value
+	node := t.NewTVar(n)
+	l.link(t, node, n, at)
+	return l.element(node)
+}
+
+// Front gives the first element of the list, nil when the list is empty.
+func (l *TList) Front(t *Transaction) *TListElement {
+	return l.element(l.readNode(t, l.root).next)
+}
+
+// Back gives the last element of the list, nil when the list is empty.
+func (l *TList) Back(t *Transaction) *TListElement {
+	return l.element(l.readNode(t, l.root).prev)
+}
+
+// Len gives the number of elements in the list. It walks and reads the whole list.
+func (l *TList) Len(t *Transaction) int {
+	n := 0
+	for c := l.Cursor(t); c.Next(); {
+		n++
+	}
+	return n
+}
+
+// PushFront inserts the `value` at the front of the list.
+func (l *TList) PushFront(t *Transaction, value Value) *TListElement {
+	return l.insertAfter(t, value, l.root)
+}
+
+// PushBack inserts the `value` at the back of the list.
+func (l *TList) PushBack(t *Transaction, value Value) *TListElement {
+	return l.insertAfter(t, value, l.readNode(t, l.root).prev)
+}
+
+// InsertBefore inserts the `value` right before the element `mark`.
+// The mark must be an element of the list that hasn't been removed.
+func (l *TList) InsertBefore(t *Transaction, value Value, mark *TListElement) *TListElement {
+	return l.insertAfter(t, value, l.readNode(t, mark.node).prev)
+}
+
+// InsertAfter inserts the `value` right after the element `mark`.
+// The mark must be an element of the list that hasn't been removed.
+func (l *TList) InsertAfter(t *Transaction, value Value, mark *TListElement) *TListElement {
+	return l.insertAfter(t, value, mark.node)
+}
+
+// Remove removes the element `e` from the list. It returns false when the element
+// was already removed.
+func (l *TList) Remove(t *Transaction, e *TListElement) bool {
+	_, ok := l.unlink(t, e.node)
+	return ok
+}
+
+// MoveToFront moves the element `e` to the front of the list. It returns false when
+// the element was already removed.
+func (l *TList) MoveToFront(t *Transaction, e *TListElement) bool {
+	n, ok := l.unlink(t, e.node)
+	if ok {
+		l.link(t, e.node, n, l.root)
+	}
+	return ok
+}
+
+// MoveToBack moves the element `e` to the back of the list. It returns false when
+// the element was already removed.
+func (l *TList) MoveToBack(t *Transaction, e *TListElement) bool {
+	n, ok := l.unlink(t, e.node)
+	if ok {
+		l.link(t, e.node, n, l.readNode(t, l.root).prev)
+	}
+	return ok
+}
+
+// Cursor gives a cursor positioned off the list. Calling Next moves it to the front
+// of the list and calling Prev moves it to the back of the list.
+func (l *TList) Cursor(t *Transaction) *TListCursor {
+	c := new(TListCursor)
+	c.list = l
+	c.t = t
+	c.at = l.root
+	return c
+}
+
+// CursorAt gives a cursor positioned at the element `e`.
+func (l *TList) CursorAt(t *Transaction, e *TListElement) *TListCursor {
+	c := l.Cursor(t)
+	c.at = e.node
+	return c
+}
+
+// ------------------------------------------------------------------------
+
+// Value gives the value of the element.
+func (e *TListElement) Value(t *Transaction) Value {
+	return t.Read(e.node).(*listNode).value
+}
+
+// SetValue replaces the value of the element.
+func (e *TListElement) SetValue(t *Transaction, value Value) bool {
+	n := t.Read(e.node).(*listNode)
+	n.value = value
+	return t.Write(e.node, n)
+}
+
+// ------------------------------------------------------------------------
+
+// Next moves the cursor to the next element. It returns false when the cursor
+// moved past the back of the list, a further Next starts again from the front.
+// It also returns false when the element the cursor is at has been removed, the
+// cursor is then off the list.
+func (c *TListCursor) Next() bool {
+	return c.move(c.list.readNode(c.t, c.at).next)
+}
+
+// Prev moves the cursor to the previous element. It returns false when the cursor
+// moved past the front of the list, a further Prev starts again from the back.
+// It also returns false when the element the cursor is at has been removed, the
+// cursor is then off the list.
+func (c *TListCursor) Prev() bool {
+	return c.move(c.list.readNode(c.t, c.at).prev)
+}
+
+// move moves the cursor to the node `to`. A nil node is the link of a removed node,
+// the cursor is moved off the list.
+func (c *TListCursor) move(to TVar) bool {
+	if to == nil {
+		c.at = c.list.root
+		return false
+	}
+	c.at = to
+	return c.at != c.list.root
+}
+
+// Element gives the element the cursor is at, nil when it is off the list.
+func (c *TListCursor) Element() *TListElement {
+	return c.list.element(c.at)
+}
+
+// Value gives the value of the element the cursor is at, nil when it is off the list.
+func (c *TListCursor) Value() Value {
+	return c.list.readNode(c.t, c.at).value
+}
@@ -0,0 +1,190 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// list_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:21:17 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:59:38 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewTListIsComplete(t *testing.T) {
+	s := New()
+	s.EnableChangefeed(16)
+	sub, err := s.Subscribe(s.Version() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// the subscriber copies the root as soon as its creation is recorded
+	created := make(chan *listNode)
+	go func() {
+		record, err := sub.Next(context.Background())
+		if err != nil {
+			created <- nil
+			return
+		}
+		created <- record.Changes[0].New.(*listNode)
+	}()
+
+	l := NewTList(s)
+	if root := <-created; root == nil || root.prev != l.root || root.next != l.root {
+		t.Fatal("the root of a new list was shared before it was linked to itself")
+	}
+
+	s.Do(func(t *Transaction) bool {
+		l.PushBack(t, counterValue(1))
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		if l.Front(tx) == nil || l.Front(tx).node != l.Back(tx).node {
+			t.Error("the pushed element isn't the only element of the list")
+		}
+		return true
+	})
+}
+
+// valuesOf gives the values of the list from the front to the back.
+func valuesOf(t *Transaction, l *TList) (values []int) {
+	for c := l.Cursor(t); c.Next(); {
+		values = append(values, int(c.Value().(counterValue)))
+	}
+	return values
+}
+
+// valuesBackwards gives the values of the list from the back to the front.
+func valuesBackwards(t *Transaction, l *TList) (values []int) {
+	for c := l.Cursor(t); c.Prev(); {
+		values = append(values, int(c.Value().(counterValue)))
+	}
+	return values
+}
+
+func TestTList(t *testing.T) {
+	s := New()
+	l := NewTList(s)
+
+	var one, three *TListElement
+	s.Do(func(tx *Transaction) bool {
+		if l.Front(tx) != nil || l.Back(tx) != nil || l.Len(tx) != 0 {
+			t.Error("a new list isn't empty")
+		}
+		three = l.PushBack(tx, counterValue(3))
+		one = l.PushFront(tx, counterValue(1))
+		l.InsertBefore(tx, counterValue(2), three)
+		l.InsertAfter(tx, counterValue(4), three)
+		return true
+	})
+
+	s.Do(func(tx *Transaction) bool {
+		if got := valuesOf(tx, l); !equalInts(got, []int{1, 2, 3, 4}) {
+			t.Errorf("forwards: got %v, want [1 2 3 4]", got)
+		}
+		if got := valuesBackwards(tx, l); !equalInts(got, []int{4, 3, 2, 1}) {
+			t.Errorf("backwards: got %v, want [4 3 2 1]", got)
+		}
+		if n := l.Len(tx); n != 4 {
+			t.Errorf("Len: got %d, want 4", n)
+		}
+
+		l.MoveToBack(tx, one)
+		l.MoveToFront(tx, three)
+		three.SetValue(tx, counterValue(30))
+		if got := valuesOf(tx, l); !equalInts(got, []int{30, 2, 4, 1}) {
+			t.Errorf("after the moves: got %v, want [30 2 4 1]", got)
+		}
+
+		if !l.Remove(tx, one) || l.Remove(tx, one) {
+			t.Error("Remove didn't remove the element exactly once")
+		}
+		if l.MoveToFront(tx, one) || l.MoveToBack(tx, one) {
+			t.Error("a removed element was moved")
+		}
+		if got := valuesOf(tx, l); !equalInts(got, []int{30, 2, 4}) {
+			t.Errorf("after the removal: got %v, want [30 2 4]", got)
+		}
+		return true
+	})
+}
+
+func TestTListCursorStopsAtARemovedElement(t *testing.T) {
+	s := New()
+	l := NewTList(s)
+
+	var e, f *TListElement
+	s.Do(func(t *Transaction) bool {
+		l.PushBack(t, counterValue(1))
+		e = l.PushBack(t, counterValue(2))
+		f = l.PushBack(t, counterValue(3))
+		l.PushBack(t, counterValue(4))
+		return true
+	})
+
+	// removed by the transaction walking the list
+	s.Do(func(tx *Transaction) bool {
+		c := l.CursorAt(tx, e)
+		l.Remove(tx, e)
+		if c.Next() || c.Element() != nil {
+			t.Error("Next moved on from an element removed by the transaction")
+		}
+		if !c.Next() || c.Value() != counterValue(1) {
+			t.Error("Next after stopping didn't start again from the front")
+		}
+		return true
+	})
+
+	// removed by another transaction while the cursor is at it
+	attempts := 0
+	s.Do(func(tx *Transaction) bool {
+		attempts++
+		c := l.CursorAt(tx, f)
+		if attempts == 1 {
+			s.Do(func(t *Transaction) bool { return l.Remove(t, f) })
+		}
+		if c.Prev() || c.Element() != nil {
+			t.Errorf("attempt %d: Prev moved on from an element removed by another transaction", attempts)
+		}
+		return true
+	})
+	s.Do(func(tx *Transaction) bool {
+		if got := valuesOf(tx, l); !equalInts(got, []int{1, 4}) {
+			t.Errorf("got %v, want [1 4]", got)
+		}
+		return true
+	})
+}
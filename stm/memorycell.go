@@ -32,13 +32,12 @@
 // memorycell.go
 // @author Sidharth Mishra
 // @created Thu Mar 29 2018 00:23:26 GMT-0700 (PDT)
-// @last-modified Sun Apr 15 2018 09:57:38 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
 //
 
 package stm
 
 import (
-	"fmt"
 	"sync"
 
 	"github.com/satori/go.uuid" // for UUID support
@@ -46,15 +45,24 @@ import (
 
 // memoryCell represents a memory cell where the data is stored.
 type memoryCell struct {
-	id          string        // The unique identity of the memory cell. Helps in getting it hashed
-	data        Value         // The contents of the memory cell.
-	memCellLock *sync.RWMutex // A read-write lock for obtaining more granular locking.
+	id          string                   // The unique identity of the memory cell. Helps in getting it hashed
+	name        string                   // The name of the memory cell given by the consumer, empty if none.
+	stm         *STM                     // The STM the memory cell belongs to.
+	data        Value                    // The contents of the memory cell.
+	memCellLock *sync.RWMutex            // A read-write lock for obtaining more granular locking.
+	compute     func(*Transaction) Value // The function computing the contents, only for computed TVars.
+	validators  []Validator              // The validators checked against every new value of the contents.
+	lastWriter  uint64                   // The ID of the transaction that last wrote the contents, guarded by the commit lock.
+	lastVersion uint64                   // The version of the STM after the last write of the contents, guarded by the commit lock and the memCellLock.
+	history     []cellVersion            // The past contents of the memory cell kept for the time-travel reads, guarded by the memCellLock.
 }
 
-// newMemCell is a memory cell constructor. It creates and initializes a new memory cell.
-func newMemCell(data Value) (memCell *memoryCell) {
+// newMemCell is a memory cell constructor. It creates and initializes a new memory cell
+// of the `stm`.
+func newMemCell(stm *STM, data Value) (memCell *memoryCell) {
 	memCell = new(memoryCell)
 	memCell.id = uuid.NewV4().String() // generates a new v4 UUID string for ID
+	memCell.stm = stm
 	memCell.data = data
 	memCell.memCellLock = new(sync.RWMutex)
 	return memCell
@@ -76,8 +84,10 @@ func (memCell *memoryCell) write(newData Value) {
 	memCell.data = newData             // update the contents of the memory cell
 }
 
-// toString gives back a string representation of the memory cell instance.
-func (memCell *memoryCell) toString() string {
-	// return fmt.Sprintf("MemoryCell#(%s)", memCell.id)
-	return fmt.Sprintf(`{"id": %v, "data": %v}`, memCell.id, memCell.data)
+// label gives the name of the memory cell, or its ID when it has no name.
+func (memCell *memoryCell) label() string {
+	if memCell.name != "" {
+		return memCell.name
+	}
+	return memCell.id
 }
@@ -0,0 +1,244 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// metrics.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:46:40 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:15:52 GMT-0700 (PDT)
+//
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// ContentType is the content type of the OpenMetrics text format served by the Collector.
+const ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// latencyBuckets are the upper bounds of the buckets of the latency histograms, in seconds.
+var latencyBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// retryBuckets are the upper bounds of the buckets of the retries histogram.
+var retryBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200}
+
+// Collector collects the metrics of an STM and serves them over HTTP in the OpenMetrics
+// text format. It is the metrics sink of the STM, and it reads the STM's statistics
+// when it is scraped.
+type Collector struct {
+	stm           *stm.STM          // the STM whose metrics are collected
+	lock          *sync.Mutex       // guards the histograms and the conflicts
+	commitLatency *histogram        // the durations of the committed transactions
+	lockWait      *histogram        // the times the commits waited for the commit lock
+	retries       *histogram        // the retries of the completed transactions
+	conflicts     map[string]uint64 // the number of conflicts per memory cell name, "" for the unnamed ones
+}
+
+// histogram is a cumulative histogram of observations.
+type histogram struct {
+	bounds []float64 // the upper bounds of the buckets, the +Inf bucket is implied
+	counts []uint64  // the number of observations in each bucket, not cumulative
+	count  uint64    // the number of observations
+	sum    float64   // the sum of the observations
+}
+
+// newHistogram creates a new empty histogram with the bucket upper `bounds`.
+func newHistogram(bounds []float64) *histogram {
+	h := new(histogram)
+	h.bounds = bounds
+	h.counts = make([]uint64, len(bounds)+1)
+	return h
+}
+
+// observe adds the observation `v` to the histogram.
+func (h *histogram) observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.bounds, v)]++
+	h.count++
+	h.sum += v
+}
+
+// New creates a collector for the STM and makes it the metrics sink of the STM.
+func New(s *stm.STM) *Collector {
+	c := new(Collector)
+	c.stm = s
+	c.lock = new(sync.Mutex)
+	c.commitLatency = newHistogram(latencyBuckets)
+	c.lockWait = newHistogram(latencyBuckets)
+	c.retries = newHistogram(retryBuckets)
+	c.conflicts = make(map[string]uint64)
+	s.SetMetricsSink(c)
+	return c
+}
+
+// TransactionStarted makes Collector conform to the stm.MetricsSink interface.
+func (c *Collector) TransactionStarted(id uint64) {}
+
+// AttemptAborted counts the conflicts of the aborted attempt per memory cell. The IDs of
+// the memory cells without a name would make a new series of every memory cell, so they
+// share the series with the empty name, which no named memory cell can collide with.
+func (c *Collector) AttemptAborted(id uint64, reason stm.AbortReason, err error) {
+	conflict, ok := err.(*stm.ConflictError)
+	if !ok {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, cf := range conflict.Conflicts {
+		c.conflicts[cf.Name]++
+	}
+}
+
+// CommitLockWaited observes the time a commit waited for the commit lock.
+func (c *Collector) CommitLockWaited(wait time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.lockWait.observe(wait.Seconds())
+}
+
+// TransactionCompleted observes the latency of a committed transaction and the
+// retries of every completed transaction.
+func (c *Collector) TransactionCompleted(report *stm.TransactionReport) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if report.Err == nil {
+		c.commitLatency.observe(report.Duration.Seconds())
+	}
+	c.retries.observe(float64(report.Attempts - 1))
+}
+
+// ServeHTTP makes Collector conform to the http.Handler interface, it serves the metrics.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	c.WriteTo(w)
+}
+
+// WriteTo writes the metrics to `w` in the OpenMetrics text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	s := c.stm.Stats()
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	writeMetric(cw, "stm_transactions", "counter", "Transactions started.")
+	fmt.Fprintf(cw, "stm_transactions_total %d\n", s.Transactions)
+	writeMetric(cw, "stm_active_transactions", "gauge", "Transactions running right now.")
+	fmt.Fprintf(cw, "stm_active_transactions %d\n", s.Active)
+	writeMetric(cw, "stm_commits", "counter", "Transactions committed.")
+	fmt.Fprintf(cw, "stm_commits_total %d\n", s.Commits)
+	writeMetric(cw, "stm_failures", "counter", "Transactions given up on without committing.")
+	fmt.Fprintf(cw, "stm_failures_total %d\n", s.Failures)
+	writeMetric(cw, "stm_aborts", "counter", "Attempts of transactions rolled back, by reason.")
+	reasons := make([]stm.AbortReason, 0, len(s.Aborts))
+	for reason := range s.Aborts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i] < reasons[j] })
+	for _, reason := range reasons {
+		fmt.Fprintf(cw, "stm_aborts_total{reason=\"%s\"} %d\n", escape(reason.String()), s.Aborts[reason])
+	}
+	writeMetric(cw, "stm_read_set_size", "counter", "Memory cells read by the committed transactions.")
+	fmt.Fprintf(cw, "stm_read_set_size_total %d\n", s.ReadSetSize)
+	writeMetric(cw, "stm_write_set_size", "counter", "Memory cells written by the committed transactions.")
+	fmt.Fprintf(cw, "stm_write_set_size_total %d\n", s.WriteSetSize)
+
+	c.lock.Lock()
+	writeMetric(cw, "stm_commit_latency_seconds", "histogram", "Time from the start to the commit of the committed transactions.")
+	writeHistogram(cw, "stm_commit_latency_seconds", c.commitLatency)
+	writeMetric(cw, "stm_commit_lock_wait_seconds", "histogram", "Time the commits waited for the commit lock.")
+	writeHistogram(cw, "stm_commit_lock_wait_seconds", c.lockWait)
+	writeMetric(cw, "stm_transaction_retries", "histogram", "Retries of the completed transactions.")
+	writeHistogram(cw, "stm_transaction_retries", c.retries)
+	writeMetric(cw, "stm_conflicts", "counter", "Conflicts on commit, by memory cell name, the unnamed memory cells together under the empty name.")
+	cells := make([]string, 0, len(c.conflicts))
+	for cell := range c.conflicts {
+		cells = append(cells, cell)
+	}
+	sort.Strings(cells)
+	for _, cell := range cells {
+		fmt.Fprintf(cw, "stm_conflicts_total{tvar=\"%s\"} %d\n", escape(cell), c.conflicts[cell])
+	}
+	c.lock.Unlock()
+
+	fmt.Fprint(cw, "# EOF\n")
+	if cw.err == nil {
+		cw.err = bw.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// writeMetric writes the metadata of the metric family `name`.
+func writeMetric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# TYPE %s %s\n# HELP %s %s\n", name, kind, name, escape(help))
+}
+
+// writeHistogram writes the samples of the histogram `h` of the metric family `name`.
+func writeHistogram(w io.Writer, name string, h *histogram) {
+	cumulative := uint64(0)
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, bound, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, h.sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// escape escapes a label value or a help text for the OpenMetrics text format.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// countingWriter counts the bytes written and remembers the first error.
+type countingWriter struct {
+	w   io.Writer // the underlying writer
+	n   int64     // the number of bytes written
+	err error     // the first error, nothing is written after it
+}
+
+// Write makes countingWriter conform to the io.Writer interface.
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
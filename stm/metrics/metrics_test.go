@@ -0,0 +1,149 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// metrics_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:25:13 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:15:52 GMT-0700 (PDT)
+//
+
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+func TestConflictsOfUnnamedCellsShareASeries(t *testing.T) {
+	c := New(stm.New())
+	for i := 0; i < 3; i++ {
+		c.AttemptAborted(1, stm.AbortConflict, &stm.ConflictError{Conflicts: []stm.Conflict{
+			{Cell: "cell-" + string(rune('a'+i))},
+			{Cell: "cell-z", Name: "balance"},
+			{Cell: "cell-y", Name: "unnamed"},
+		}})
+	}
+
+	var out bytes.Buffer
+	if _, err := c.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, sample := range []string{
+		"stm_conflicts_total{tvar=\"\"} 3\n",
+		"stm_conflicts_total{tvar=\"balance\"} 3\n",
+		"stm_conflicts_total{tvar=\"unnamed\"} 3\n",
+	} {
+		if !strings.Contains(out.String(), sample) {
+			t.Errorf("missing sample %q in\n%s", sample, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "cell-") {
+		t.Errorf("the IDs of the unnamed memory cells are labels in\n%s", out.String())
+	}
+}
+
+// amount is the value of the memory cells of the tests.
+type amount int
+
+// MakeCopy makes amount conform to the stm.Value interface.
+func (a amount) MakeCopy() stm.Value {
+	return a
+}
+
+// IsEqual makes amount conform to the stm.Value interface.
+func (a amount) IsEqual(v stm.Value) bool {
+	other, ok := v.(amount)
+	return ok && other == a
+}
+
+// sampleLine is a line of a sample in the OpenMetrics text format.
+var sampleLine = regexp.MustCompile(`^[a-z_]+(\{[a-z]+="([^"\\]|\\.)*"\})? [0-9.e+-]+$`)
+
+func TestServeHTTP(t *testing.T) {
+	s := stm.New()
+	c := New(s)
+	balance := s.NewNamedTVar("balance", amount(0))
+
+	attempts := 0
+	s.Do(func(tx *stm.Transaction) bool {
+		attempts++
+		tx.Read(balance)
+		if attempts == 1 {
+			// a concurrent transaction conflicts with the first attempt
+			s.Do(func(t *stm.Transaction) bool { return t.Write(balance, amount(1)) })
+		}
+		return tx.Write(balance, amount(2))
+	})
+
+	server := httptest.NewServer(c)
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type: got %q, want %q", got, ContentType)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(body)
+
+	if !strings.HasSuffix(out, "\n# EOF\n") {
+		t.Errorf("the exposition doesn't end with # EOF:\n%s", out)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if !strings.HasPrefix(line, "# ") && !sampleLine.MatchString(line) {
+			t.Errorf("malformed line %q", line)
+		}
+	}
+	for _, sample := range []string{
+		"# TYPE stm_commits counter\n",
+		"stm_transactions_total 2\n",
+		"stm_commits_total 2\n",
+		"stm_aborts_total{reason=\"conflict\"} 1\n",
+		"stm_conflicts_total{tvar=\"balance\"} 1\n",
+		"stm_transaction_retries_count 2\n",
+		"stm_transaction_retries_bucket{le=\"+Inf\"} 2\n",
+	} {
+		if !strings.Contains(out, sample) {
+			t.Errorf("missing sample %q in\n%s", sample, out)
+		}
+	}
+}
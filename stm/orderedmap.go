@@ -0,0 +1,275 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// orderedmap.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:37:26 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:54:39 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"cmp"
+	"math/rand"
+)
+
+// orderedMapMaxLevel is the maximum number of levels in the skip list backing the TOrderedMap.
+const orderedMapMaxLevel = 16
+
+// TOrderedMap is a transactional ordered map. It is a skip list where every node
+// is its own memory cell and every value is in a memory cell of its own. A transaction
+// only reads the nodes on its search path and only writes the predecessors of the node
+// it inserts or deletes, so transactions working in different key ranges rarely conflict.
+// Updating the value of an existing key only writes the value's memory cell.
+//
+// The head node is the predecessor of the first node at every level and every search
+// reads it. Inserting a key in front of the first node at a level, or deleting the
+// first node at a level, writes the head and conflicts with every concurrent
+// transaction on the map. The smallest keys are where that happens, a map whose
+// minimum keeps changing -- a queue of deadlines -- conflicts like a single TVar.
+type TOrderedMap[K cmp.Ordered] struct {
+	head TVar // the head node of the skip list, it has links at all levels
+}
+
+// orderedMapNode is a node of the skip list. It is stored in a memory cell.
+type orderedMapNode[K cmp.Ordered] struct {
+	key   K      // the key of the node, the head node has the zero key
+	value TVar   // the memory cell holding the value, nil for the head node
+	next  []TVar // the forward links at every level of this node, nil marks the end
+}
+
+// MakeCopy makes orderedMapNode conform to the Value interface.
+func (n *orderedMapNode[K]) MakeCopy() Value {
+	nn := new(orderedMapNode[K])
+	nn.key = n.key
+	nn.value = n.value
+	nn.next = make([]TVar, len(n.next))
+	copy(nn.next, n.next)
+	return nn
+}
+
+// IsEqual checks the equality between two nodes. Two nodes are equal when they have
+// the same key, the same value cell and the same forward links.
+func (n *orderedMapNode[K]) IsEqual(v Value) bool {
+	vv, ok := v.(*orderedMapNode[K])
+	if !ok {
+		return false
+	}
+	if vv.key != n.key || vv.value != n.value || len(vv.next) != len(n.next) {
+		return false
+	}
+	for i := range n.next {
+		if vv.next[i] != n.next[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewTOrderedMap creates a new empty ordered map managed by the STM.
+func NewTOrderedMap[K cmp.Ordered](stm *STM) *TOrderedMap[K] {
+	m := new(TOrderedMap[K])
+	head := new(orderedMapNode[K])
+	head.next = make([]TVar, orderedMapMaxLevel)
+	m.head = stm.NewTVar(head)
+	return m
+}
+
+// readNode reads the node referenced by the `tVar` in the transaction.
+func (m *TOrderedMap[K]) readNode(t *Transaction, tVar TVar) *orderedMapNode[K] {
+	return t.Read(tVar).(*orderedMapNode[K])
+}
+
+// search finds the predecessors at every level of the first node with a key greater
+// than or equal to the `key`. The returned nodes are the transaction's copies, the
+// same predecessor at several levels is the same copy. It also gives back the
+// successor at the bottom level, nil when there is none.
+func (m *TOrderedMap[K]) search(t *Transaction, key K) (preds []TVar, predNodes []*orderedMapNode[K], succ TVar, succNode *orderedMapNode[K]) {
+	preds = make([]TVar, orderedMapMaxLevel)
+	predNodes = make([]*orderedMapNode[K], orderedMapMaxLevel)
+
+	x, xn := m.head, m.readNode(t, m.head)
+	for lvl := orderedMapMaxLevel - 1; lvl >= 0; lvl-- {
+		for xn.next[lvl] != nil {
+			nn := m.readNode(t, xn.next[lvl])
+			if nn.key >= key {
+				break
+			}
+			x, xn = xn.next[lvl], nn
+		}
+		preds[lvl], predNodes[lvl] = x, xn
+	}
+
+	if succ = predNodes[0].next[0]; succ != nil {
+		succNode = m.readNode(t, succ)
+	}
+	return preds, predNodes, succ, succNode
+}
+
+// randomLevel picks the number of levels for a new node, each level is 4 times
+// less likely than the one below it.
+func (m *TOrderedMap[K]) randomLevel() int {
+	lvl := 1
+	for lvl < orderedMapMaxLevel && rand.Intn(4) == 0 {
+		lvl++
+	}
+	return lvl
+}
+
+// Get gives the value mapped to the `key` and true, or nil and false when the key is absent.
+func (m *TOrderedMap[K]) Get(t *Transaction, key K) (Value, bool) {
+	_, _, _, succNode := m.search(t, key)
+	if succNode == nil || succNode.key != key {
+		return nil, false
+	}
+	return t.Read(succNode.value), true
+}
+
+// Put maps the `key` to the `value`, replacing the previous value of the key if any.
+func (m *TOrderedMap[K]) Put(t *Transaction, key K, value Value) bool {
+	preds, predNodes, _, succNode := m.search(t, key)
+	if succNode != nil && succNode.key == key {
+		return t.Write(succNode.value, value)
+	}
+
+	node := new(orderedMapNode[K])
+	node.key = key
+	node.value = t.NewTVar(value)
+	node.next = make([]TVar, m.randomLevel())
+	for lvl := range node.next {
+		node.next[lvl] = predNodes[lvl].next[lvl]
+	}
+	nodeVar := t.NewTVar(node)
+
+	for lvl := range node.next {
+		predNodes[lvl].next[lvl] = nodeVar
+	}
+	for lvl := range node.next {
+		t.Write(preds[lvl], predNodes[lvl])
+	}
+	return true
+}
+
+// Delete removes the `key` from the map. It returns false when the key was absent.
+func (m *TOrderedMap[K]) Delete(t *Transaction, key K) bool {
+	preds, predNodes, _, succNode := m.search(t, key)
+	if succNode == nil || succNode.key != key {
+		return false
+	}
+
+	for lvl := range succNode.next {
+		predNodes[lvl].next[lvl] = succNode.next[lvl]
+	}
+	for lvl := range succNode.next {
+		t.Write(preds[lvl], predNodes[lvl])
+	}
+	return true
+}
+
+// Min gives the smallest key and its value. The last result is false when the map is empty.
+func (m *TOrderedMap[K]) Min(t *Transaction) (K, Value, bool) {
+	var zero K
+	first := m.readNode(t, m.head).next[0]
+	if first == nil {
+		return zero, nil, false
+	}
+	n := m.readNode(t, first)
+	return n.key, t.Read(n.value), true
+}
+
+// Max gives the largest key and its value. The last result is false when the map is empty.
+func (m *TOrderedMap[K]) Max(t *Transaction) (K, Value, bool) {
+	var zero K
+	x, xn := m.head, m.readNode(t, m.head)
+	for lvl := orderedMapMaxLevel - 1; lvl >= 0; lvl-- {
+		for xn.next[lvl] != nil {
+			x = xn.next[lvl]
+			xn = m.readNode(t, x)
+		}
+	}
+	if x == m.head {
+		return zero, nil, false
+	}
+	return xn.key, t.Read(xn.value), true
+}
+
+// Ceiling gives the smallest key greater than or equal to the `key` and its value.
+// The last result is false when there is no such key.
+func (m *TOrderedMap[K]) Ceiling(t *Transaction, key K) (K, Value, bool) {
+	var zero K
+	_, _, _, succNode := m.search(t, key)
+	if succNode == nil {
+		return zero, nil, false
+	}
+	return succNode.key, t.Read(succNode.value), true
+}
+
+// Floor gives the largest key less than or equal to the `key` and its value.
+// The last result is false when there is no such key.
+func (m *TOrderedMap[K]) Floor(t *Transaction, key K) (K, Value, bool) {
+	var zero K
+	preds, predNodes, _, succNode := m.search(t, key)
+	if succNode != nil && succNode.key == key {
+		return succNode.key, t.Read(succNode.value), true
+	}
+	if preds[0] == m.head {
+		return zero, nil, false
+	}
+	return predNodes[0].key, t.Read(predNodes[0].value), true
+}
+
+// Range calls `fn` for every key in [from, to) and its value in ascending key order.
+// The iteration stops early when `fn` returns false.
+func (m *TOrderedMap[K]) Range(t *Transaction, from, to K, fn func(key K, value Value) bool) {
+	_, _, succ, succNode := m.search(t, from)
+	for succ != nil && succNode.key < to {
+		if !fn(succNode.key, t.Read(succNode.value)) {
+			return
+		}
+		if succ = succNode.next[0]; succ != nil {
+			succNode = m.readNode(t, succ)
+		}
+	}
+}
+
+// ForEach calls `fn` for every key and its value in ascending key order.
+// The iteration stops early when `fn` returns false.
+func (m *TOrderedMap[K]) ForEach(t *Transaction, fn func(key K, value Value) bool) {
+	succ := m.readNode(t, m.head).next[0]
+	for succ != nil {
+		n := m.readNode(t, succ)
+		if !fn(n.key, t.Read(n.value)) {
+			return
+		}
+		succ = n.next[0]
+	}
+}
@@ -0,0 +1,273 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// orderedmap_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:54:39 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:54:39 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// newOrderedMap creates an ordered map holding the `keys`, every key mapped to itself.
+func newOrderedMap(s *STM, keys ...int) *TOrderedMap[int] {
+	m := NewTOrderedMap[int](s)
+	s.Do(func(t *Transaction) bool {
+		for _, key := range keys {
+			m.Put(t, key, counterValue(key))
+		}
+		return true
+	})
+	return m
+}
+
+// keysOf gives the keys of the map in the order ForEach visits them.
+func keysOf(s *STM, m *TOrderedMap[int]) (keys []int) {
+	s.Do(func(t *Transaction) bool {
+		keys = keys[:0]
+		m.ForEach(t, func(key int, value Value) bool {
+			keys = append(keys, key)
+			return true
+		})
+		return true
+	})
+	return keys
+}
+
+func TestTOrderedMapEmpty(t *testing.T) {
+	s := New()
+	m := newOrderedMap(s)
+	s.Do(func(tx *Transaction) bool {
+		if _, ok := m.Get(tx, 1); ok {
+			t.Error("Get found a key in an empty map")
+		}
+		if m.Delete(tx, 1) {
+			t.Error("Delete removed a key from an empty map")
+		}
+		if _, _, ok := m.Min(tx); ok {
+			t.Error("Min found a key in an empty map")
+		}
+		if _, _, ok := m.Max(tx); ok {
+			t.Error("Max found a key in an empty map")
+		}
+		if _, _, ok := m.Ceiling(tx, 1); ok {
+			t.Error("Ceiling found a key in an empty map")
+		}
+		if _, _, ok := m.Floor(tx, 1); ok {
+			t.Error("Floor found a key in an empty map")
+		}
+		m.Range(tx, -100, 100, func(key int, value Value) bool {
+			t.Errorf("Range visited %d in an empty map", key)
+			return true
+		})
+		return true
+	})
+}
+
+func TestTOrderedMapPutGetDelete(t *testing.T) {
+	s := New()
+	keys := rand.Perm(200)
+	m := newOrderedMap(s, keys...)
+
+	got := keysOf(s, m)
+	if len(got) != len(keys) {
+		t.Fatalf("%d keys in the map, want %d", len(got), len(keys))
+	}
+	for i, key := range got {
+		if key != i {
+			t.Fatalf("key %d at %d, the keys aren't in ascending order", key, i)
+		}
+	}
+
+	s.Do(func(tx *Transaction) bool {
+		if value, ok := m.Get(tx, 42); !ok || value != counterValue(42) {
+			t.Errorf("Get(42): got %v, %v, want 42, true", value, ok)
+		}
+		if _, ok := m.Get(tx, 200); ok {
+			t.Error("Get found the absent key 200")
+		}
+		m.Put(tx, 42, counterValue(-42))
+		if value, _ := m.Get(tx, 42); value != counterValue(-42) {
+			t.Errorf("Get(42) after replacing its value: got %v, want -42", value)
+		}
+		for key := 0; key < 200; key += 2 {
+			if !m.Delete(tx, key) {
+				t.Errorf("Delete(%d) didn't find the key", key)
+			}
+		}
+		if m.Delete(tx, 0) {
+			t.Error("Delete removed the key 0 twice")
+		}
+		return true
+	})
+
+	got = keysOf(s, m)
+	if len(got) != 100 {
+		t.Fatalf("%d keys left after deleting the even ones, want 100", len(got))
+	}
+	for i, key := range got {
+		if key != 2*i+1 {
+			t.Fatalf("key %d at %d, want %d", key, i, 2*i+1)
+		}
+	}
+}
+
+func TestTOrderedMapBounds(t *testing.T) {
+	s := New()
+	m := newOrderedMap(s, 30, 10, 20)
+
+	type bound struct {
+		name  string
+		find  func(tx *Transaction, key int) (int, Value, bool)
+		key   int
+		found int // the key found, -1 when none is
+	}
+	s.Do(func(tx *Transaction) bool {
+		bounds := []bound{
+			{"Ceiling", m.Ceiling, 5, 10},
+			{"Ceiling", m.Ceiling, 10, 10},
+			{"Ceiling", m.Ceiling, 11, 20},
+			{"Ceiling", m.Ceiling, 30, 30},
+			{"Ceiling", m.Ceiling, 31, -1},
+			{"Floor", m.Floor, 9, -1},
+			{"Floor", m.Floor, 10, 10},
+			{"Floor", m.Floor, 25, 20},
+			{"Floor", m.Floor, 30, 30},
+			{"Floor", m.Floor, 40, 30},
+		}
+		for _, b := range bounds {
+			key, value, ok := b.find(tx, b.key)
+			switch {
+			case b.found < 0 && ok:
+				t.Errorf("%s(%d): got %d, want none", b.name, b.key, key)
+			case b.found >= 0 && (!ok || key != b.found || value != counterValue(b.found)):
+				t.Errorf("%s(%d): got %d, %v, %v, want %d", b.name, b.key, key, value, ok, b.found)
+			}
+		}
+
+		if key, value, ok := m.Min(tx); !ok || key != 10 || value != counterValue(10) {
+			t.Errorf("Min: got %d, %v, %v, want 10", key, value, ok)
+		}
+		if key, value, ok := m.Max(tx); !ok || key != 30 || value != counterValue(30) {
+			t.Errorf("Max: got %d, %v, %v, want 30", key, value, ok)
+		}
+		return true
+	})
+}
+
+func TestTOrderedMapRange(t *testing.T) {
+	s := New()
+	m := newOrderedMap(s, 10, 20, 30, 40)
+
+	ranges := []struct {
+		from, to int
+		want     []int
+	}{
+		{10, 40, []int{10, 20, 30}}, // to is left out
+		{11, 41, []int{20, 30, 40}},
+		{0, 100, []int{10, 20, 30, 40}},
+		{20, 20, nil},
+		{30, 10, nil},
+		{41, 100, nil},
+	}
+	s.Do(func(tx *Transaction) bool {
+		for _, r := range ranges {
+			var got []int
+			m.Range(tx, r.from, r.to, func(key int, value Value) bool {
+				if value != counterValue(key) {
+					t.Errorf("Range(%d, %d): %d mapped to %v", r.from, r.to, key, value)
+				}
+				got = append(got, key)
+				return true
+			})
+			if !equalInts(got, r.want) {
+				t.Errorf("Range(%d, %d): got %v, want %v", r.from, r.to, got, r.want)
+			}
+		}
+
+		var visited int
+		m.Range(tx, 0, 100, func(key int, value Value) bool {
+			visited++
+			return key < 20
+		})
+		if visited != 2 {
+			t.Errorf("Range visited %d keys after fn returned false, want 2", visited)
+		}
+		return true
+	})
+}
+
+func TestTOrderedMapConcurrentPuts(t *testing.T) {
+	s := New()
+	m := newOrderedMap(s)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := g*50 + i
+				s.Do(func(t *Transaction) bool { return m.Put(t, key, counterValue(key)) })
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	got := keysOf(s, m)
+	if len(got) != 200 {
+		t.Fatalf("%d keys in the map, want 200", len(got))
+	}
+	for i, key := range got {
+		if key != i {
+			t.Fatalf("key %d at %d, the keys aren't in ascending order", key, i)
+		}
+	}
+}
+
+// equalInts checks the two slices hold the same ints in the same order.
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
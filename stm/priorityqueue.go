@@ -0,0 +1,212 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// priorityqueue.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:38:15 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:55:42 GMT-0700 (PDT)
+//
+
+package stm
+
+import "cmp"
+
+// TPriorityQueue is a transactional min-priority queue. It is a binary heap where
+// every slot of the heap is its own memory cell, so a push or a pop only copies the
+// slots on the path it sifts the entry along instead of the whole heap. Every push and
+// pop also writes the heap header holding the size, and every operation reads it, so
+// the transactions changing a queue conflict with all the others using it.
+type TPriorityQueue[P cmp.Ordered] struct {
+	heap TVar // the heap header -- size and the slots of the heap
+}
+
+// priorityQueueHeap is the header of the heap. It is stored in a memory cell.
+type priorityQueueHeap struct {
+	size  int    // the number of entries in the heap
+	slots []TVar // the slots of the heap, only the first `size` slots are in use
+}
+
+// MakeCopy makes priorityQueueHeap conform to the Value interface.
+func (h *priorityQueueHeap) MakeCopy() Value {
+	nh := new(priorityQueueHeap)
+	nh.size = h.size
+	nh.slots = make([]TVar, len(h.slots))
+	copy(nh.slots, h.slots)
+	return nh
+}
+
+// IsEqual checks the equality between two heap headers.
+func (h *priorityQueueHeap) IsEqual(v Value) bool {
+	vv, ok := v.(*priorityQueueHeap)
+	if !ok {
+		return false
+	}
+	if vv.size != h.size || len(vv.slots) != len(h.slots) {
+		return false
+	}
+	for i := range h.slots {
+		if vv.slots[i] != h.slots[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// priorityQueueEntry is an entry in a slot of the heap.
+type priorityQueueEntry[P cmp.Ordered] struct {
+	priority P     // the priority of the entry, lower is served first
+	value    Value // the queued value
+}
+
+// MakeCopy makes priorityQueueEntry conform to the Value interface.
+func (e *priorityQueueEntry[P]) MakeCopy() Value {
+	ne := new(priorityQueueEntry[P])
+	ne.priority = e.priority
+	ne.value = e.value.MakeCopy()
+	return ne
+}
+
+// IsEqual checks the equality between two entries.
+func (e *priorityQueueEntry[P]) IsEqual(v Value) bool {
+	vv, ok := v.(*priorityQueueEntry[P])
+	if !ok {
+		return false
+	}
+	return vv.priority == e.priority && vv.value.IsEqual(e.value)
+}
+
+// NewTPriorityQueue creates a new empty priority queue managed by the STM.
+func NewTPriorityQueue[P cmp.Ordered](stm *STM) *TPriorityQueue[P] {
+	q := new(TPriorityQueue[P])
+	q.heap = stm.NewTVar(new(priorityQueueHeap))
+	return q
+}
+
+// readEntry reads the entry in the slot referenced by the `tVar`.
+func (q *TPriorityQueue[P]) readEntry(t *Transaction, tVar TVar) *priorityQueueEntry[P] {
+	return t.Read(tVar).(*priorityQueueEntry[P])
+}
+
+// Len gives the number of entries in the queue.
+func (q *TPriorityQueue[P]) Len(t *Transaction) int {
+	return t.Read(q.heap).(*priorityQueueHeap).size
+}
+
+// Push adds the `value` to the queue with the given `priority`.
+func (q *TPriorityQueue[P]) Push(t *Transaction, priority P, value Value) bool {
+	h := t.Read(q.heap).(*priorityQueueHeap)
+
+	e := new(priorityQueueEntry[P])
+	e.priority = priority
+	e.value = value
+
+	if h.size == len(h.slots) {
+		h.slots = append(h.slots, t.NewTVar(e)) // the heap needs a new slot
+	}
+
+	// sift the entry up from the new last slot
+	i := h.size
+	for i > 0 {
+		p := (i - 1) / 2
+		pe := q.readEntry(t, h.slots[p])
+		if pe.priority <= e.priority {
+			break
+		}
+		t.Write(h.slots[i], pe)
+		i = p
+	}
+	t.Write(h.slots[i], e)
+
+	h.size++
+	return t.Write(q.heap, h)
+}
+
+// PeekMin gives the priority and value of the entry with the lowest priority without
+// removing it. The last result is false when the queue is empty.
+func (q *TPriorityQueue[P]) PeekMin(t *Transaction) (P, Value, bool) {
+	var zero P
+	h := t.Read(q.heap).(*priorityQueueHeap)
+	if h.size == 0 {
+		return zero, nil, false
+	}
+	e := q.readEntry(t, h.slots[0])
+	return e.priority, e.value, true
+}
+
+// PopMin removes the entry with the lowest priority and gives its priority and value.
+// The last result is false when the queue is empty.
+func (q *TPriorityQueue[P]) PopMin(t *Transaction) (P, Value, bool) {
+	var zero P
+	h := t.Read(q.heap).(*priorityQueueHeap)
+	if h.size == 0 {
+		return zero, nil, false
+	}
+
+	top := q.readEntry(t, h.slots[0])
+	h.size--
+	if h.size > 0 {
+		// sift the last entry down from the root
+		e := q.readEntry(t, h.slots[h.size])
+		i := 0
+		for {
+			c := 2*i + 1
+			if c >= h.size {
+				break
+			}
+			ce := q.readEntry(t, h.slots[c])
+			if r := c + 1; r < h.size {
+				if re := q.readEntry(t, h.slots[r]); re.priority < ce.priority {
+					c, ce = r, re
+				}
+			}
+			if e.priority <= ce.priority {
+				break
+			}
+			t.Write(h.slots[i], ce)
+			i = c
+		}
+		t.Write(h.slots[i], e)
+	}
+
+	t.Write(q.heap, h)
+	return top.priority, top.value, true
+}
+
+// PopMinWait is the blocking PopMin. When the queue is empty the transaction is
+// retried once another transaction has changed the queue, see Transaction.Retry.
+// The queue has been read by then, so the transaction always has something to wait on.
+func (q *TPriorityQueue[P]) PopMinWait(t *Transaction) (P, Value) {
+	priority, value, ok := q.PopMin(t)
+	if !ok {
+		t.Retry()
+	}
+	return priority, value
+}
@@ -0,0 +1,148 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// priorityqueue_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:55:42 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:55:42 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestTPriorityQueuePopsInPriorityOrder(t *testing.T) {
+	s := New()
+	q := NewTPriorityQueue[int](s)
+
+	priorities := make([]int, 100)
+	for i := range priorities {
+		priorities[i] = rand.Intn(50) // with ties
+	}
+	s.Do(func(t *Transaction) bool {
+		for _, p := range priorities {
+			q.Push(t, p, counterValue(p))
+		}
+		return true
+	})
+	sort.Ints(priorities)
+
+	s.Do(func(tx *Transaction) bool {
+		if n := q.Len(tx); n != len(priorities) {
+			t.Errorf("Len: got %d, want %d", n, len(priorities))
+		}
+		for i, want := range priorities {
+			p, value, ok := q.PopMin(tx)
+			if !ok || p != want || value != counterValue(want) {
+				t.Fatalf("PopMin %d: got %d, %v, %v, want %d", i, p, value, ok, want)
+			}
+		}
+		if _, _, ok := q.PopMin(tx); ok {
+			t.Error("PopMin gave an entry from an empty queue")
+		}
+		return true
+	})
+}
+
+func TestTPriorityQueuePushesAndPopsInterleaved(t *testing.T) {
+	s := New()
+	q := NewTPriorityQueue[int](s)
+
+	var want []int // the priorities in the queue, sorted
+	for i := 0; i < 200; i++ {
+		if len(want) > 0 && rand.Intn(3) == 0 {
+			var p int
+			s.Do(func(t *Transaction) bool {
+				p, _, _ = q.PopMin(t)
+				return true
+			})
+			if p != want[0] {
+				t.Fatalf("PopMin: got %d, want %d", p, want[0])
+			}
+			want = want[1:]
+			continue
+		}
+		p := rand.Intn(1000)
+		s.Do(func(t *Transaction) bool { return q.Push(t, p, counterValue(p)) })
+		want = append(want, p)
+		sort.Ints(want)
+	}
+}
+
+func TestTPriorityQueuePeekMin(t *testing.T) {
+	s := New()
+	q := NewTPriorityQueue[string](s)
+
+	s.Do(func(tx *Transaction) bool {
+		if _, _, ok := q.PeekMin(tx); ok {
+			t.Error("PeekMin gave an entry from an empty queue")
+		}
+		q.Push(tx, "b", counterValue(2))
+		q.Push(tx, "a", counterValue(1))
+		q.Push(tx, "c", counterValue(3))
+		for i := 0; i < 2; i++ {
+			if p, value, ok := q.PeekMin(tx); !ok || p != "a" || value != counterValue(1) {
+				t.Errorf("PeekMin: got %q, %v, %v, want a", p, value, ok)
+			}
+		}
+		if n := q.Len(tx); n != 3 {
+			t.Errorf("Len after PeekMin: got %d, want 3", n)
+		}
+		return true
+	})
+}
+
+func TestTPriorityQueueAbortedPushIsUndone(t *testing.T) {
+	s := New()
+	q := NewTPriorityQueue[int](s)
+	s.Do(func(t *Transaction) bool { return q.Push(t, 2, counterValue(2)) })
+
+	attempts := 0
+	s.Do(func(t *Transaction) bool {
+		attempts++
+		q.Push(t, 1, counterValue(1))
+		return attempts > 1 // the first attempt fails after pushing
+	})
+
+	s.Do(func(tx *Transaction) bool {
+		if n := q.Len(tx); n != 2 {
+			t.Errorf("Len: got %d, want 2", n)
+		}
+		if p, _, _ := q.PopMin(tx); p != 1 {
+			t.Errorf("PopMin: got %d, want 1", p)
+		}
+		return true
+	})
+}
@@ -0,0 +1,358 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// client.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:08:13 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:33:01 GMT-0700 (PDT)
+//
+
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// the bounds of the time a transaction backs off for before running again, see Client.Do
+const (
+	minBackoff = time.Millisecond
+	maxBackoff = 100 * time.Millisecond
+)
+
+// Client runs transactions on the TVars served by a Server.
+type Client struct {
+	url   string       // the URL the server is served at
+	codec stm.Codec    // encodes and decodes the values on the wire
+	http  *http.Client // makes the requests
+}
+
+// TVar references a TVar served by a Server, by its name.
+type TVar struct {
+	name string // the name of the TVar
+}
+
+// Name gives the name of the TVar.
+func (tVar *TVar) Name() string {
+	return tVar.name
+}
+
+// Transaction is a transaction of a Client. It has the Read and Write of a
+// stm.Transaction, not the rest of its surface: there is no Commute, the update is a
+// Read then a Write; no Retry, the action returns false to be run again after a
+// back off; and no NewTVar, the TVars are created up front with Client.NewTVar. It
+// reads the TVars from the server the first time they are read, and keeps its writes
+// until it is committed.
+type Transaction struct {
+	client  *Client              // the client running the transaction
+	ctx     context.Context      // the context of the requests
+	label   string               // the label of the transaction, empty if none
+	reads   map[string]wireValue // the values read from the server, as they were read
+	values  map[string]stm.Value // the values read from the server, decoded
+	writes  map[string]stm.Value // the values written
+	err     error                // the first error the transaction ran into
+	running bool                 // the action is run by Client.Do, a failed read abandons it
+}
+
+// abortSignal is raised by a failed read in an action run by Client.Do, to abandon the
+// action, see Transaction.attempt.
+type abortSignal struct{}
+
+// NewClient makes a new client of the server at the `url`, e.g. "http://localhost:7070".
+// The values are encoded on the wire by the `codec`, usually stm.DefaultCodecs.
+func NewClient(url string, codec stm.Codec) *Client {
+	c := new(Client)
+	c.url = strings.TrimSuffix(url, "/")
+	c.codec = codec
+	c.http = http.DefaultClient
+	return c
+}
+
+// NewTVar creates the named TVar on the server with the initial `value`. When the TVar
+// exists it is referenced as it is, so the processes sharing a TVar all create it.
+func (c *Client) NewTVar(ctx context.Context, name string, value stm.Value) (*TVar, error) {
+	wv, err := c.encode(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.call(ctx, http.MethodPost, "/v1/tvars", createRequest{Name: name, Value: wv}, nil); err != nil {
+		return nil, err
+	}
+	return c.TVar(name), nil
+}
+
+// TVar references the TVar with the name on the server, without checking it exists.
+func (c *Client) TVar(name string) *TVar {
+	tVar := new(TVar)
+	tVar.name = name
+	return tVar
+}
+
+// Version gives the version of the STM of the server.
+func (c *Client) Version(ctx context.Context) (uint64, error) {
+	var resp versionResponse
+	err := c.call(ctx, http.MethodGet, "/v1/version", nil, &resp)
+	return resp.Version, err
+}
+
+// Begin begins a transaction. It is committed with Commit, or abandoned by dropping it.
+func (c *Client) Begin(ctx context.Context) *Transaction {
+	return c.BeginLabelled(ctx, "")
+}
+
+// BeginLabelled is Begin for a transaction with a label, the label of the transaction
+// committed on the server.
+func (c *Client) BeginLabelled(ctx context.Context, label string) *Transaction {
+	t := new(Transaction)
+	t.client = c
+	t.ctx = ctx
+	t.label = label
+	t.reads = make(map[string]wireValue)
+	t.values = make(map[string]stm.Value)
+	t.writes = make(map[string]stm.Value)
+	return t
+}
+
+// Do performs the transactional action like stm.STM.Do, running it again as long as
+// its commit conflicts with other commits, and the action when it returns false. It
+// backs off for a random time before running it again, up to twice as long every time,
+// so the clients conflicting on the same TVars don't conflict again. It returns the
+// error the transaction was aborted with, nil when it has committed, and the error of
+// the context when it is done first.
+func (c *Client) Do(ctx context.Context, action func(*Transaction) bool) error {
+	return c.DoLabelled(ctx, "", action)
+}
+
+// DoLabelled is Do for a transaction with a label.
+func (c *Client) DoLabelled(ctx context.Context, label string, action func(*Transaction) bool) error {
+	for backoff := minBackoff; ; backoff = min(2*backoff, maxBackoff) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t := c.BeginLabelled(ctx, label)
+		status, err := t.attempt(action)
+		if err == nil && status {
+			err = t.Commit()
+		}
+		if errors.Is(err, ErrConflict) || (err == nil && !status) {
+			if err := sleep(ctx, time.Duration(rand.Int63n(int64(backoff)))); err != nil {
+				return err
+			}
+			continue
+		}
+		return err
+	}
+}
+
+// sleep waits for the duration `d`, or until the context is done and gives its error.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// attempt runs the action once. A failed read abandons the action, and the error of
+// the read is returned. The other panics of the action go on.
+func (t *Transaction) attempt(action func(*Transaction) bool) (status bool, err error) {
+	t.running = true
+	defer func() {
+		t.running = false
+		if r := recover(); r != nil {
+			if _, ok := r.(abortSignal); !ok {
+				panic(r)
+			}
+			status, err = false, t.err
+		}
+	}()
+	return action(t), nil
+}
+
+// fail records the error the transaction ran into. The action run by Client.Do is
+// abandoned, otherwise the read gives nil.
+func (t *Transaction) fail(err error) stm.Value {
+	t.err = err
+	if t.running {
+		panic(abortSignal{})
+	}
+	return nil
+}
+
+// Read reads the value of the TVar, a copy of it. Like a stm.Transaction it sees its
+// own writes. When the value can't be read the transaction fails with the error, see
+// Err: the action run by Client.Do is abandoned, otherwise Read returns nil.
+func (t *Transaction) Read(tVar *TVar) stm.Value {
+	if value, ok := t.writes[tVar.name]; ok {
+		return value.MakeCopy()
+	}
+	if value, ok := t.values[tVar.name]; ok {
+		return value.MakeCopy()
+	}
+	if t.err != nil {
+		return nil
+	}
+
+	var resp readResponse
+	if err := t.client.call(t.ctx, http.MethodPost, "/v1/read", readRequest{Names: []string{tVar.name}}, &resp); err != nil {
+		return t.fail(err)
+	}
+	wv := resp.Values[tVar.name]
+	value, err := t.client.codec.Decode(wv.Type, wv.Data)
+	if err != nil {
+		return t.fail(fmt.Errorf("remote: decoding TVar %s: %w", tVar.name, err))
+	}
+	t.reads[tVar.name] = wv
+	t.values[tVar.name] = value
+	return value.MakeCopy()
+}
+
+// Write writes the new value of the TVar, sent to the server at commit.
+func (t *Transaction) Write(tVar *TVar, value stm.Value) bool {
+	if t.err != nil {
+		return false
+	}
+	t.writes[tVar.name] = value
+	return true
+}
+
+// Err gives the error the transaction ran into, nil if none.
+func (t *Transaction) Err() error {
+	return t.err
+}
+
+// Commit commits the transaction. It returns ErrConflict when a TVar the transaction
+// read has been changed by another commit since, the transaction is then run again.
+// A transaction that only read is committed too, the commit checks its reads were
+// consistent.
+func (t *Transaction) Commit() error {
+	if t.err != nil {
+		return t.err
+	}
+	req := commitRequest{Label: t.label, Reads: t.reads, Writes: make(map[string]wireValue, len(t.writes))}
+	for name, value := range t.writes {
+		wv, err := t.client.encode(name, value)
+		if err != nil {
+			return err
+		}
+		req.Writes[name] = wv
+	}
+	return t.client.call(t.ctx, http.MethodPost, "/v1/commit", req, nil)
+}
+
+// encode encodes the value of the TVar with the name for the wire.
+func (c *Client) encode(name string, value stm.Value) (wireValue, error) {
+	typ, data, err := c.codec.Encode(value)
+	if err != nil {
+		return wireValue{}, fmt.Errorf("remote: encoding TVar %s: %w", name, err)
+	}
+	return wireValue{Type: typ, Data: data}, nil
+}
+
+// call makes a request to the server with the JSON body `req`, and decodes the JSON body
+// of the response into `resp` if it isn't nil. The requests refused are errors.
+func (c *Client) call(ctx context.Context, method, path string, req, resp interface{}) error {
+	var body io.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return err
+	}
+	if req != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var e errorResponse
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("remote: %s %s: %s", method, path, res.Status)
+		}
+		return &serverError{msg: e.Error, err: errorOfCode(e.Code)}
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resp)
+}
+
+// serverError is the error of a request refused by the server.
+type serverError struct {
+	msg string // the reason the server gave
+	err error  // the error the reason is an instance of, nil if none
+}
+
+// Error makes serverError conform to the error interface.
+func (e *serverError) Error() string {
+	return e.msg
+}
+
+// Unwrap gives the error the reason is an instance of, for errors.Is.
+func (e *serverError) Unwrap() error {
+	return e.err
+}
+
+// errorOfCode gives the error of the error code, nil if none.
+func errorOfCode(code string) error {
+	switch code {
+	case codeConflict:
+		return ErrConflict
+	case codeNotFound:
+		return ErrNotFound
+	case codeReadOnly:
+		return stm.ErrReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// protocol.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:08:13 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:08:13 GMT-0700 (PDT)
+//
+
+// Package remote serves an STM over HTTP, so the processes of a service can share
+// transactional state. A client runs its transactions optimistically: it reads the
+// named TVars from the server as it goes, keeps its writes to itself, and sends the
+// values it read and the values it wrote to the server to commit. The server commits
+// the writes in a transaction of its own, only if the TVars still hold the values the
+// client read, the same validation a local transaction gets at commit. Otherwise the
+// commit is refused as a conflict and the client runs the transaction again.
+//
+// The protocol is JSON over HTTP:
+//
+//	POST /v1/tvars   {"name": ..., "value": ...}               creates a named TVar, unless it exists
+//	POST /v1/read    {"names": [...]}                          reads named TVars, consistently
+//	POST /v1/commit  {"label": ..., "reads": {}, "writes": {}} commits a transaction
+//	GET  /v1/version                                           gives the version of the STM
+//
+// The values are encoded by a stm.Codec, a value is its type and the encoded data.
+package remote
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+var (
+	// ErrConflict is the reason a commit is refused, the TVars the transaction read
+	// have been changed by another commit since. Client.Do runs the transaction again.
+	ErrConflict = errors.New("remote: the transaction conflicts with another commit")
+
+	// ErrNotFound is the reason a request is refused, a TVar it names doesn't exist.
+	ErrNotFound = errors.New("remote: no such TVar")
+)
+
+// wireValue is a value on the wire, encoded by the codec.
+type wireValue struct {
+	Type string `json:"type"` // the type the codec tagged the value with
+	Data []byte `json:"data"` // the encoded value, base64 in JSON
+}
+
+// createRequest is the body of POST /v1/tvars.
+type createRequest struct {
+	Name  string    `json:"name"`  // the name of the TVar
+	Value wireValue `json:"value"` // the initial value of the TVar
+}
+
+// createResponse is the body of the response to POST /v1/tvars.
+type createResponse struct {
+	Created bool `json:"created"` // the TVar was created, false if it existed
+}
+
+// readRequest is the body of POST /v1/read.
+type readRequest struct {
+	Names []string `json:"names"` // the names of the TVars read
+}
+
+// readResponse is the body of the response to POST /v1/read.
+type readResponse struct {
+	Values map[string]wireValue `json:"values"` // the values of the TVars by name
+}
+
+// commitRequest is the body of POST /v1/commit.
+type commitRequest struct {
+	Label  string               `json:"label,omitempty"` // the label of the transaction
+	Reads  map[string]wireValue `json:"reads"`           // the values the transaction read, by name
+	Writes map[string]wireValue `json:"writes"`          // the values the transaction wrote, by name
+}
+
+// versionResponse is the body of the response to GET /v1/version.
+type versionResponse struct {
+	Version uint64 `json:"version"` // the version of the STM
+}
+
+// errorResponse is the body of the responses to the requests refused.
+type errorResponse struct {
+	Error string `json:"error"`          // the reason the request was refused
+	Code  string `json:"code,omitempty"` // what the client makes of it, see the error codes
+}
+
+// the error codes of the errorResponse
+const (
+	codeConflict = "conflict"
+	codeNotFound = "not_found"
+	codeReadOnly = "read_only"
+)
+
+// ------------------------------------------------------------------------
+
+// Blob is a value kept encoded, as its type and data. A server hosting Blobs doesn't
+// need to know the Go types of the values its clients share, see BlobCodec. Blobs are
+// equal when their encodings are, so the encodings of the values must be deterministic.
+type Blob struct {
+	Type string // the type the codec of the clients tagged the value with
+	Data []byte // the encoded value
+}
+
+// IsEqual makes Blob conform to the stm.Value interface.
+func (b Blob) IsEqual(v stm.Value) bool {
+	other, ok := v.(Blob)
+	return ok && b.Type == other.Type && bytes.Equal(b.Data, other.Data)
+}
+
+// MakeCopy makes Blob conform to the stm.Value interface.
+func (b Blob) MakeCopy() stm.Value {
+	b.Data = append([]byte(nil), b.Data...)
+	return b
+}
+
+// BlobCodec is the codec of a server hosting Blobs, e.g. stmd. It encodes a Blob as
+// the encoded value it holds and decodes every encoded value into a Blob, so the
+// values pass through the server, and its write-ahead log, as the clients encoded them.
+var BlobCodec stm.Codec = blobCodec{}
+
+// blobCodec is the codec of the Blobs.
+type blobCodec struct{}
+
+// Encode makes blobCodec conform to the stm.Codec interface.
+func (blobCodec) Encode(value stm.Value) (string, []byte, error) {
+	b, ok := value.(Blob)
+	if !ok {
+		return "", nil, stm.ErrNoCodec
+	}
+	return b.Type, b.Data, nil
+}
+
+// Decode makes blobCodec conform to the stm.Codec interface.
+func (blobCodec) Decode(typ string, data []byte) (stm.Value, error) {
+	return Blob{Type: typ, Data: append([]byte(nil), data...)}, nil
+}
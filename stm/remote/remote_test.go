@@ -0,0 +1,246 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// remote_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:34:37 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:33:01 GMT-0700 (PDT)
+//
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// amount is the value of the TVars shared by the tests.
+type amount int
+
+// MakeCopy makes amount conform to the stm.Value interface.
+func (a amount) MakeCopy() stm.Value {
+	return a
+}
+
+// IsEqual makes amount conform to the stm.Value interface.
+func (a amount) IsEqual(v stm.Value) bool {
+	other, ok := v.(amount)
+	return ok && other == a
+}
+
+// codecs encodes the amounts, on both ends.
+var codecs = stm.NewCodecRegistry()
+
+func init() {
+	codecs.Register("remote.amount", stm.JSONEncoding[amount]())
+}
+
+// serve serves a new STM over HTTP on the loopback, and gives back a client of it.
+func serve(t *testing.T) (*stm.STM, *Client) {
+	s := stm.New()
+	ts := httptest.NewServer(NewServer(s, codecs))
+	t.Cleanup(ts.Close)
+	return s, NewClient(ts.URL, codecs)
+}
+
+// add adds `delta` to the TVar in a transaction of the client.
+func add(ctx context.Context, c *Client, tVar *TVar, delta amount) error {
+	return c.Do(ctx, func(t *Transaction) bool {
+		return t.Write(tVar, t.Read(tVar).(amount)+delta)
+	})
+}
+
+// read reads the TVar in a transaction of the client.
+func read(t *testing.T, c *Client, tVar *TVar) amount {
+	t.Helper()
+	var a amount
+	if err := c.Do(context.Background(), func(t *Transaction) bool {
+		a = t.Read(tVar).(amount)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestClientCreatesReadsAndCommits(t *testing.T) {
+	_, c := serve(t)
+	ctx := context.Background()
+
+	balance, err := c.NewTVar(ctx, "balance", amount(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NewTVar(ctx, "balance", amount(99)); err != nil {
+		t.Fatal(err)
+	}
+	if a := read(t, c, balance); a != 10 {
+		t.Fatalf("balance %d, want 10 -- created once", a)
+	}
+
+	if err := add(ctx, c, balance, 5); err != nil {
+		t.Fatal(err)
+	}
+	if a := read(t, c, balance); a != 15 {
+		t.Errorf("balance %d after the commit, want 15", a)
+	}
+	if err := add(ctx, c, c.TVar("missing"), 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("committed to a TVar that doesn't exist, error %v", err)
+	}
+}
+
+func TestClientRetriesConflicts(t *testing.T) {
+	s, c := serve(t)
+	ctx := context.Background()
+	balance, err := c.NewTVar(ctx, "balance", amount(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first attempt conflicts with a commit on the server
+	attempts := 0
+	err = c.Do(ctx, func(tx *Transaction) bool {
+		attempts++
+		a := tx.Read(balance).(amount)
+		if attempts == 1 {
+			tVar, _ := s.Lookup("balance")
+			s.Do(func(t *stm.Transaction) bool { return t.Write(tVar, amount(100)) })
+		}
+		return tx.Write(balance, a+1)
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("%d attempts, error %v, want 2 attempts", attempts, err)
+	}
+	if a := read(t, c, balance); a != 101 {
+		t.Errorf("balance %d, want 101", a)
+	}
+
+	// the concurrent clients conflict and retry until every increment is committed
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if err := add(ctx, c, balance, 1); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if a := read(t, c, balance); a != 181 {
+		t.Errorf("balance %d after the concurrent increments, want 181", a)
+	}
+}
+
+func TestClientGivesUpWithTheContext(t *testing.T) {
+	s, c := serve(t)
+	balance, err := c.NewTVar(context.Background(), "balance", amount(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tVar, _ := s.Lookup("balance")
+
+	// every attempt conflicts
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = c.Do(ctx, func(tx *Transaction) bool {
+		a := tx.Read(balance).(amount)
+		s.Do(func(t *stm.Transaction) bool { return t.Write(tVar, t.Read(tVar).(amount)+1) })
+		return tx.Write(balance, a)
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do returned %v, want the error of the context", err)
+	}
+}
+
+func TestReadOnlyServerRefusesWrites(t *testing.T) {
+	s, c := serve(t)
+	ctx := context.Background()
+	balance, err := c.NewTVar(ctx, "balance", amount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetReadOnly(true)
+
+	if err := add(ctx, c, balance, 1); !errors.Is(err, stm.ErrReadOnly) {
+		t.Errorf("committed to a read-only server, error %v", err)
+	}
+	if _, err := c.NewTVar(ctx, "other", amount(1)); !errors.Is(err, stm.ErrReadOnly) {
+		t.Errorf("created a TVar on a read-only server, error %v", err)
+	}
+	if a := read(t, c, balance); a != 1 {
+		t.Errorf("balance %d on the read-only server, want 1", a)
+	}
+}
+
+func TestActionPanicsGoOn(t *testing.T) {
+	_, c := serve(t)
+	ctx := context.Background()
+	balance, err := c.NewTVar(ctx, "balance", amount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recovered %v, want the panic of the action", r)
+			}
+		}()
+		c.Do(ctx, func(t *Transaction) bool {
+			t.Read(balance)
+			panic("boom")
+		})
+	}()
+
+	// a failed read abandons the action run by Do, it returns nil to the others
+	ran := false
+	err = c.Do(ctx, func(t *Transaction) bool {
+		t.Read(c.TVar("missing"))
+		ran = true
+		return true
+	})
+	if !errors.Is(err, ErrNotFound) || ran {
+		t.Errorf("Do returned %v, the action went on %t, want ErrNotFound and the action abandoned", err, ran)
+	}
+	tx := c.Begin(ctx)
+	if value := tx.Read(c.TVar("missing")); value != nil || !errors.Is(tx.Err(), ErrNotFound) {
+		t.Errorf("read %v, error %v, want nil and ErrNotFound", value, tx.Err())
+	}
+}
@@ -0,0 +1,288 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// server.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:08:13 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:33:35 GMT-0700 (PDT)
+//
+
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// maxBodySize is the largest request body the server reads.
+const maxBodySize = 32 << 20
+
+// Server serves the named TVars of an STM over HTTP, see the package documentation.
+type Server struct {
+	stm   *stm.STM            // the STM served
+	codec stm.Codec           // encodes and decodes the values on the wire
+	lock  *sync.Mutex         // guards the TVars
+	tvars map[string]stm.TVar // the TVars looked up so far, by name
+	mux   *http.ServeMux      // routes the requests
+}
+
+// NewServer makes a new server of the named TVars of the STM, the values are encoded
+// on the wire by the `codec`. The names of the TVars served must be unique.
+func NewServer(s *stm.STM, codec stm.Codec) *Server {
+	srv := new(Server)
+	srv.stm = s
+	srv.codec = codec
+	srv.lock = new(sync.Mutex)
+	srv.tvars = make(map[string]stm.TVar)
+	srv.mux = http.NewServeMux()
+	srv.mux.HandleFunc("/v1/tvars", only(http.MethodPost, srv.create))
+	srv.mux.HandleFunc("/v1/read", only(http.MethodPost, srv.read))
+	srv.mux.HandleFunc("/v1/commit", only(http.MethodPost, srv.commit))
+	srv.mux.HandleFunc("/v1/version", only(http.MethodGet, srv.version))
+	return srv
+}
+
+// ServeHTTP makes Server conform to the http.Handler interface.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mux.ServeHTTP(w, r)
+}
+
+// create creates a named TVar, unless it exists.
+func (srv *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "", errors.New("remote: a TVar needs a name"))
+		return
+	}
+	value, err := srv.codec.Decode(req.Value.Type, req.Value.Data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", err)
+		return
+	}
+
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if _, ok := srv.lookup(req.Name); ok {
+		writeJSON(w, http.StatusOK, createResponse{Created: false})
+		return
+	}
+	if srv.stm.ReadOnly() {
+		writeError(w, http.StatusConflict, codeReadOnly, fmt.Errorf("remote: creating TVar %s: %w", req.Name, stm.ErrReadOnly))
+		return
+	}
+	srv.tvars[req.Name] = srv.stm.NewNamedTVar(req.Name, value)
+	writeJSON(w, http.StatusCreated, createResponse{Created: true})
+}
+
+// read reads named TVars in a transaction, so the values are consistent.
+func (srv *Server) read(w http.ResponseWriter, r *http.Request) {
+	var req readRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	tvars, err := srv.resolve(req.Names)
+	if err != nil {
+		writeError(w, http.StatusNotFound, codeNotFound, err)
+		return
+	}
+
+	var values map[string]stm.Value
+	err = srv.stm.DoLabelled("remote.read", func(t *stm.Transaction) bool {
+		values = make(map[string]stm.Value, len(tvars))
+		for name, tVar := range tvars {
+			values[name] = t.Read(tVar)
+		}
+		return true
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	resp := readResponse{Values: make(map[string]wireValue, len(values))}
+	for name, value := range values {
+		typ, data, err := srv.codec.Encode(value)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "", fmt.Errorf("remote: encoding TVar %s: %w", name, err))
+			return
+		}
+		resp.Values[name] = wireValue{Type: typ, Data: data}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// commit commits the writes of a client transaction, if the TVars it read still hold
+// the values it read.
+func (srv *Server) commit(w http.ResponseWriter, r *http.Request) {
+	var req commitRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	reads, err := srv.decodeValues(req.Reads)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	writes, err := srv.decodeValues(req.Writes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	tvars, err := srv.resolve(append(names(req.Reads), names(req.Writes)...))
+	if err != nil {
+		writeError(w, http.StatusNotFound, codeNotFound, err)
+		return
+	}
+
+	var conflict string
+	err = srv.stm.DoLabelled(req.Label, func(t *stm.Transaction) bool {
+		conflict = ""
+		for name, value := range reads {
+			if !t.Read(tvars[name]).IsEqual(value) {
+				conflict = name
+				return true // commits nothing, the reads are validated all the same
+			}
+		}
+		for name, value := range writes {
+			t.Write(tvars[name], value)
+		}
+		return true
+	})
+	switch {
+	case errors.Is(err, stm.ErrReadOnly):
+		writeError(w, http.StatusConflict, codeReadOnly, err)
+	case err != nil:
+		writeError(w, http.StatusUnprocessableEntity, "", err) // rejected by the validators
+	case conflict != "":
+		writeError(w, http.StatusConflict, codeConflict, fmt.Errorf("%w: TVar %s has changed", ErrConflict, conflict))
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// version gives the version of the STM.
+func (srv *Server) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, versionResponse{Version: srv.stm.Version()})
+}
+
+// resolve looks the TVars with the names up.
+func (srv *Server) resolve(names []string) (map[string]stm.TVar, error) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	tvars := make(map[string]stm.TVar, len(names))
+	for _, name := range names {
+		tVar, ok := srv.lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		tvars[name] = tVar
+	}
+	return tvars, nil
+}
+
+// lookup looks the TVar with the name up, in the STM when it hasn't been so far. The
+// caller must hold the lock.
+func (srv *Server) lookup(name string) (stm.TVar, bool) {
+	if tVar, ok := srv.tvars[name]; ok {
+		return tVar, true
+	}
+	tVar, ok := srv.stm.Lookup(name)
+	if ok {
+		srv.tvars[name] = tVar
+	}
+	return tVar, ok
+}
+
+// decodeValues decodes the values on the wire.
+func (srv *Server) decodeValues(wire map[string]wireValue) (map[string]stm.Value, error) {
+	values := make(map[string]stm.Value, len(wire))
+	for name, v := range wire {
+		value, err := srv.codec.Decode(v.Type, v.Data)
+		if err != nil {
+			return nil, fmt.Errorf("remote: decoding TVar %s: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// names gives the names of the values on the wire.
+func names(wire map[string]wireValue) []string {
+	names := make([]string, 0, len(wire))
+	for name := range wire {
+		names = append(names, name)
+	}
+	return names
+}
+
+// only restricts the handler to the requests with the method.
+func only(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, "", fmt.Errorf("remote: %s %s is not allowed", r.Method, r.URL.Path))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// decodeRequest decodes the JSON body of the request into `req`. When it can't, the
+// request is refused and it returns false.
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "", fmt.Errorf("remote: malformed request: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes the response with the JSON body.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError refuses the request for the reason `err`.
+func writeError(w http.ResponseWriter, status int, code string, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error(), Code: code})
+}
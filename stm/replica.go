@@ -0,0 +1,207 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// replica.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:03:36 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrReadOnly is the reason the transactions writing to a read-only STM are aborted.
+	ErrReadOnly = errors.New("stm: the STM is read-only")
+
+	// ErrVersionGap is the reason a change record is refused by Apply, it isn't the
+	// record of the version following the version of the STM.
+	ErrVersionGap = errors.New("stm: the change record doesn't follow the version of the STM")
+
+	// ErrWritable is the reason a change record is refused by Apply, the STM is writable,
+	// e.g. a replica that has been promoted, see SetReadOnly.
+	ErrWritable = errors.New("stm: the STM is writable, only a read-only STM applies change records")
+)
+
+// SetReadOnly makes the STM read-only, or writable again. The transactions of a read-only
+// STM can only read, the transactions writing to it are aborted with ErrReadOnly, and
+// only Apply changes it. It is how a replica is kept in step with its primary, and
+// making it writable again promotes it.
+func (stm *STM) SetReadOnly(readOnly bool) {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	stm.readOnly = readOnly
+}
+
+// ReadOnly checks if the STM is read-only, see SetReadOnly.
+func (stm *STM) ReadOnly() bool {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	return stm.readOnly
+}
+
+// Lookup gives the memory cell with the `name`, see NewNamedTVar. The last result is
+// false when there is none.
+func (stm *STM) Lookup(name string) (TVar, bool) {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	memCell, ok := stm.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return TVar(memCell), true
+}
+
+// lookup gives the memory cell with the name, from the index of the names. The unnamed
+// memory cells are never found. The caller must hold the commit lock.
+func (stm *STM) lookup(name string) (*memoryCell, bool) {
+	if name == "" {
+		return nil, false
+	}
+	memCell, ok := stm.names[name]
+	return memCell, ok
+}
+
+// SubscribeSnapshot takes a snapshot of the STM and subscribes to the changefeed from
+// right after it, together, so the snapshot followed by the records delivered make up
+// every commit. It is how a new replica is brought up to date.
+func (stm *STM) SubscribeSnapshot() (*Snapshot, *Subscription, error) {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	sub, err := stm.Subscribe(stm.version + 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stm.snapshot(), sub, nil
+}
+
+// Apply applies a change record of another STM, e.g. a primary this STM is a replica
+// of, as a commit at the version of the record. The memory cells are matched by their
+// IDs, the memory cells that don't exist are created with the IDs of the record. The
+// records are applied one after the other, the record must be at the version following
+// the version of the STM, the error wraps ErrVersionGap otherwise. The STM must be
+// read-only, the record is refused with ErrWritable otherwise. The validators and
+// invariants are not checked, the STM the record was made by has. Like a commit it waits
+// for the subscriptions to the changefeed of the STM, see SetSubscriberTimeout.
+func (stm *STM) Apply(record ChangeRecord) error {
+	return stm.applyRecord(record, false)
+}
+
+// ApplySnapshot applies a change record holding the contents of every memory cell of
+// another STM at the version of the record, e.g. the snapshot of its primary a replica
+// too far behind catches up from, see SubscribeSnapshot. Unlike Apply the STM jumps to
+// the version of the record, it must only be later than the version of the STM.
+func (stm *STM) ApplySnapshot(record ChangeRecord) error {
+	return stm.applyRecord(record, true)
+}
+
+// applyRecord applies the change record, or the snapshot, see Apply and ApplySnapshot.
+func (stm *STM) applyRecord(record ChangeRecord, snapshot bool) error {
+	t := newTransaction(stm, nil)
+	t.label = record.Label
+
+	stm.acquireCommitLock()
+	if !stm.readOnly {
+		stm.releaseCommitLock()
+		return ErrWritable
+	}
+	if record.Version != stm.version+1 && (!snapshot || record.Version <= stm.version) {
+		err := fmt.Errorf("%w: the record is at version %d, the STM at version %d", ErrVersionGap, record.Version, stm.version)
+		stm.releaseCommitLock()
+		return err
+	}
+
+	memCells := make(map[string]*memoryCell)
+	stm.memoryLock.RLock()
+	for _, memCell := range stm.memory {
+		memCells[memCell.id] = memCell
+	}
+	stm.memoryLock.RUnlock()
+
+	newValues := make(map[*memoryCell]Value)
+	for _, change := range record.Changes {
+		if memCell, ok := memCells[change.Cell]; ok {
+			newValues[memCell] = change.New
+			continue
+		}
+		memCell := newMemCell(stm, change.New)
+		memCell.id = change.Cell
+		memCell.name = change.Name
+		memCells[change.Cell] = memCell
+		t.newCells = append(t.newCells, memCell)
+	}
+
+	version := stm.version
+	stm.version = record.Version - 1 // the versions follow the STM the record was made by
+	if err := stm.logCommit(newValues, t.newCells); err != nil {
+		stm.version = version
+		stm.releaseCommitLock()
+		return err
+	}
+	stm.version = record.Version
+	t.version = record.Version
+
+	if stm.feed.enabled() {
+		t.record = &ChangeRecord{Version: record.Version, TransactionID: record.TransactionID, Label: record.Label}
+	}
+	for _, memCell := range t.newCells {
+		memCell.lastVersion = stm.version
+		if t.record != nil {
+			t.record.Changes = append(t.record.Changes, Change{Cell: memCell.id, Name: memCell.name, Created: true, New: memCell.data})
+		}
+	}
+	for memCell, value := range newValues {
+		t.publish(memCell, value)
+	}
+	stm.addMemCells(t.newCells...)
+	for _, memCell := range t.newCells {
+		if memCell.name != "" {
+			stm.unclaimed[memCell.name] = memCell // reclaimed by name once the STM is promoted
+		}
+	}
+	if t.record != nil {
+		stm.feed.append(*t.record)
+	}
+	stm.commitCond.Broadcast() // wake up the transactions waiting for changes
+	stm.releaseCommitLock()
+
+	stm.notifyWatchers(t.changes)
+	if t.record != nil {
+		stm.feed.throttle(context.Background())
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// replica_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:33:12 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:32:01 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyRefusesAVersionGap(t *testing.T) {
+	replica := New()
+	replica.SetReadOnly(true)
+	created := ChangeRecord{Version: 1, Changes: []Change{{Cell: "c1", Name: "balance", Created: true, New: counterValue(1)}}}
+	if err := replica.Apply(created); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []uint64{1, 3} {
+		record := ChangeRecord{Version: version, Changes: []Change{{Cell: "c1", Name: "balance", New: counterValue(2)}}}
+		if err := replica.Apply(record); !errors.Is(err, ErrVersionGap) {
+			t.Errorf("applied a record at version %d to the STM at version 1, error %v", version, err)
+		}
+	}
+
+	snapshot := ChangeRecord{Version: 5, Changes: []Change{{Cell: "c1", Name: "balance", New: counterValue(5)}}}
+	if err := replica.ApplySnapshot(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.ApplySnapshot(snapshot); !errors.Is(err, ErrVersionGap) {
+		t.Errorf("applied a snapshot at the version of the STM, error %v", err)
+	}
+	balance, _ := replica.Lookup("balance")
+	if replica.Version() != 5 || !balance.(*memoryCell).read().IsEqual(counterValue(5)) {
+		t.Errorf("STM at version %d after the snapshot, want 5", replica.Version())
+	}
+}
+
+func TestReadOnlySTMOnlyGivesBackReplicatedCells(t *testing.T) {
+	replica := New()
+	replica.SetReadOnly(true)
+	replica.Apply(ChangeRecord{Version: 1, Changes: []Change{{Cell: "c1", Name: "balance", Created: true, New: counterValue(1)}}})
+
+	balance, _ := replica.Lookup("balance")
+	if tVar := replica.NewNamedTVar("balance", counterValue(0)); tVar != balance {
+		t.Error("the replicated memory cell wasn't given back")
+	}
+	for _, name := range []string{"", "orphan"} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, ErrReadOnly) {
+					t.Errorf("created memory cell %q on a read-only STM, panic %v", name, err)
+				}
+			}()
+			replica.NewNamedTVar(name, counterValue(0))
+		}()
+	}
+}
+
+func TestApplyIsRefusedByAWritableSTM(t *testing.T) {
+	s := New()
+	created := ChangeRecord{Version: 1, Changes: []Change{{Cell: "c1", Name: "balance", Created: true, New: counterValue(1)}}}
+	if err := s.Apply(created); !errors.Is(err, ErrWritable) {
+		t.Errorf("applied a record to a writable STM, error %v", err)
+	}
+	if err := s.ApplySnapshot(created); !errors.Is(err, ErrWritable) {
+		t.Errorf("applied a snapshot to a writable STM, error %v", err)
+	}
+	if s.Version() != 0 {
+		t.Errorf("STM at version %d after the refused records, want 0", s.Version())
+	}
+}
+
+func TestPromotedSTMReclaimsReplicatedCells(t *testing.T) {
+	replica := New()
+	replica.SetReadOnly(true)
+	replica.Apply(ChangeRecord{Version: 1, Changes: []Change{
+		{Cell: "c1", Name: "balance", Created: true, New: counterValue(1)},
+		{Cell: "c2", Created: true, New: counterValue(2)},
+	}})
+	balance, _ := replica.Lookup("balance")
+
+	replica.SetReadOnly(false)
+	if tVar := replica.NewNamedTVar("balance", counterValue(0)); tVar != balance || countOf(tVar) != 1 {
+		t.Error("the replicated memory cell wasn't given back once promoted")
+	}
+	expectDuplicateName(t, func() { replica.NewNamedTVar("balance", counterValue(0)) })
+}
@@ -0,0 +1,324 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// replication.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:03:36 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:32:01 GMT-0700 (PDT)
+//
+
+// Package replication replicates an STM from a primary to its followers. The primary
+// streams the records of its changefeed to every follower over a connection, and a
+// follower applies them to its own read-only STM in the order of the versions, so the
+// follower serves read-only transactions on a consistent, if slightly stale, state.
+// Replication is asynchronous, a commit on the primary doesn't wait for its followers.
+// A follower is promoted to a primary when the primary fails.
+package replication
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// ErrPromoted is returned by Follower.Follow once the follower has been promoted.
+var ErrPromoted = errors.New("replication: the follower has been promoted")
+
+// hello is the first message of a follower, the version of the STM it has applied.
+type hello struct {
+	Version uint64
+}
+
+// record is a change record sent by the primary. The first record a follower gets is a
+// snapshot of the primary, as a record of every memory cell, when the primary no longer
+// has the records the follower lacks.
+type record struct {
+	Version       uint64
+	TransactionID uint64
+	Label         string
+	Changes       []change
+	Snapshot      bool // the record is a snapshot, see stm.STM.ApplySnapshot
+}
+
+// change is the change of a memory cell in a record, the new contents encoded by the codec.
+type change struct {
+	Cell    string
+	Name    string
+	Created bool
+	Type    string
+	Data    []byte
+}
+
+// ------------------------------------------------------------------------
+
+// Primary serves the followers of an STM.
+type Primary struct {
+	stm   *stm.STM  // the STM replicated
+	codec stm.Codec // encodes the contents of the memory cells for the followers
+}
+
+// NewPrimary makes a new primary replicating the STM, the contents of the memory cells
+// are sent encoded with the `codec`. The changefeed of the STM must be enabled, its
+// capacity is how far behind a follower can reconnect from without a snapshot, and
+// how far behind the slowest follower the commits are allowed to run.
+func NewPrimary(s *stm.STM, codec stm.Codec) *Primary {
+	p := new(Primary)
+	p.stm = s
+	p.codec = codec
+	return p
+}
+
+// Serve accepts the connections of the followers on the listener and serves each of
+// them, until the listener is closed.
+func (p *Primary) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			p.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn serves a follower on the connection, until the connection fails. The
+// follower is sent the records from the version following its own, or a snapshot
+// first when the changefeed no longer has the oldest of them, see stm.STM.Subscribe.
+func (p *Primary) ServeConn(conn io.ReadWriter) error {
+	dec := gob.NewDecoder(conn)
+	enc := gob.NewEncoder(conn)
+
+	var h hello
+	if err := dec.Decode(&h); err != nil {
+		return err
+	}
+	if version := p.stm.Version(); h.Version > version {
+		return fmt.Errorf("replication: the follower is at version %d, ahead of the primary at %d", h.Version, version)
+	}
+
+	sub, err := p.stm.Subscribe(h.Version + 1)
+	if errors.Is(err, stm.ErrVersionNotRetained) {
+		// the follower is too far behind, it catches up from a snapshot
+		var snap *stm.Snapshot
+		snap, sub, err = p.stm.SubscribeSnapshot()
+		if err == nil {
+			err = p.sendSnapshot(enc, snap)
+		}
+	}
+	if err != nil {
+		if sub != nil {
+			sub.Close()
+		}
+		return err
+	}
+	defer sub.Close()
+
+	// the follower sends nothing more, a failed read is the connection going away
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		dec.Decode(new(hello))
+		cancel()
+	}()
+
+	for {
+		r, err := sub.Next(ctx)
+		if err != nil {
+			return err
+		}
+		changes, err := p.encodeChanges(r.Changes)
+		if err != nil {
+			return err
+		}
+		err = enc.Encode(record{Version: r.Version, TransactionID: r.TransactionID, Label: r.Label, Changes: changes})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sendSnapshot sends the snapshot as a record.
+func (p *Primary) sendSnapshot(enc *gob.Encoder, snap *stm.Snapshot) error {
+	changes := make([]stm.Change, len(snap.Cells))
+	for i, cell := range snap.Cells {
+		changes[i] = stm.Change{Cell: cell.ID, Name: cell.Name, New: cell.Value}
+	}
+	encoded, err := p.encodeChanges(changes)
+	if err != nil {
+		return err
+	}
+	return enc.Encode(record{Version: snap.Version, Label: "replication.snapshot", Changes: encoded, Snapshot: true})
+}
+
+// encodeChanges encodes the contents of the changes. The memory cells holding contents
+// the codec doesn't encode are not replicated.
+func (p *Primary) encodeChanges(changes []stm.Change) ([]change, error) {
+	encoded := make([]change, 0, len(changes))
+	for _, c := range changes {
+		typ, data, err := p.codec.Encode(c.New)
+		if errors.Is(err, stm.ErrNoCodec) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("replication: encoding memory cell %s: %w", c.Cell, err)
+		}
+		encoded = append(encoded, change{Cell: c.Cell, Name: c.Name, Created: c.Created, Type: typ, Data: data})
+	}
+	return encoded, nil
+}
+
+// ------------------------------------------------------------------------
+
+// Follower keeps a read-only STM in step with a primary.
+type Follower struct {
+	stm      *stm.STM      // the STM of the follower
+	codec    stm.Codec     // decodes the contents of the memory cells sent by the primary
+	lock     *sync.Mutex   // guards the connection and the promotion
+	conn     io.ReadWriter // the connection to the primary, nil when not following
+	promoted bool          // the follower has been promoted
+}
+
+// NewFollower makes a new follower keeping the STM in step with a primary, the STM is
+// made read-only, see stm.STM.SetReadOnly. It is usually a new STM, or the persisted
+// STM of a follower that is restarted.
+func NewFollower(s *stm.STM, codec stm.Codec) *Follower {
+	s.SetReadOnly(true)
+	f := new(Follower)
+	f.stm = s
+	f.codec = codec
+	f.lock = new(sync.Mutex)
+	return f
+}
+
+// STM gives the STM of the follower, for the read-only transactions.
+func (f *Follower) STM() *stm.STM {
+	return f.stm
+}
+
+// Follow follows the primary on the connection, applying the records the primary sends
+// until the connection fails or the follower is promoted. It is called again with a
+// new connection to resume following, from the version the follower has applied.
+func (f *Follower) Follow(conn io.ReadWriter) error {
+	f.lock.Lock()
+	if f.promoted {
+		f.lock.Unlock()
+		return ErrPromoted
+	}
+	f.conn = conn
+	f.lock.Unlock()
+
+	defer func() {
+		f.lock.Lock()
+		f.conn = nil
+		f.lock.Unlock()
+	}()
+
+	if err := gob.NewEncoder(conn).Encode(hello{Version: f.stm.Version()}); err != nil {
+		return err
+	}
+
+	dec := gob.NewDecoder(conn)
+	for {
+		var r record
+		if err := dec.Decode(&r); err != nil {
+			if f.isPromoted() {
+				return ErrPromoted
+			}
+			return err
+		}
+		cr, err := f.decode(r)
+		if err != nil {
+			return err
+		}
+
+		// applied without the lock, Apply may wait for the subscribers of the STM; once
+		// promoted the STM is writable and refuses the records
+		if r.Snapshot {
+			err = f.stm.ApplySnapshot(cr)
+		} else {
+			err = f.stm.Apply(cr)
+		}
+		if err != nil {
+			if f.isPromoted() {
+				return ErrPromoted
+			}
+			return err
+		}
+	}
+}
+
+// Promote stops following the primary and makes the STM of the follower writable,
+// it takes over from a failed primary. The connection to the primary is closed if it
+// is an io.Closer, and a record being applied is either applied before or refused. The
+// replicated memory cells are given back by name once, e.g. by stm.STM.NewNamedTVar.
+// The STM is given back, to be served to new followers by a Primary.
+func (f *Follower) Promote() *stm.STM {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.promoted {
+		f.promoted = true
+		if c, ok := f.conn.(io.Closer); ok {
+			c.Close()
+		}
+		f.stm.SetReadOnly(false)
+	}
+	return f.stm
+}
+
+// isPromoted checks if the follower has been promoted.
+func (f *Follower) isPromoted() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.promoted
+}
+
+// decode decodes the record sent by the primary into a change record of the STM.
+func (f *Follower) decode(r record) (stm.ChangeRecord, error) {
+	cr := stm.ChangeRecord{Version: r.Version, TransactionID: r.TransactionID, Label: r.Label}
+	cr.Changes = make([]stm.Change, len(r.Changes))
+	for i, c := range r.Changes {
+		value, err := f.codec.Decode(c.Type, c.Data)
+		if err != nil {
+			return cr, fmt.Errorf("replication: decoding memory cell %s at version %d: %w", c.Cell, r.Version, err)
+		}
+		cr.Changes[i] = stm.Change{Cell: c.Cell, Name: c.Name, Created: c.Created, New: value}
+	}
+	return cr, nil
+}
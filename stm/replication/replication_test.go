@@ -0,0 +1,232 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// replication_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:33:35 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:32:01 GMT-0700 (PDT)
+//
+
+package replication
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// amount is the value of the memory cells replicated by the tests.
+type amount int
+
+// MakeCopy makes amount conform to the stm.Value interface.
+func (a amount) MakeCopy() stm.Value {
+	return a
+}
+
+// IsEqual makes amount conform to the stm.Value interface.
+func (a amount) IsEqual(v stm.Value) bool {
+	other, ok := v.(amount)
+	return ok && other == a
+}
+
+// codecs encodes the amounts.
+var codecs = stm.NewCodecRegistry()
+
+func init() {
+	codecs.Register("replication.amount", stm.JSONEncoding[amount]())
+}
+
+// connect connects the follower to the primary over a pipe. It gives back the end of
+// the follower, closing it disconnects them, and the result of Follow.
+func connect(t *testing.T, p *Primary, f *Follower) (net.Conn, chan error) {
+	primaryEnd, followerEnd := net.Pipe()
+	t.Cleanup(func() { followerEnd.Close() })
+	go func() {
+		defer primaryEnd.Close()
+		p.ServeConn(primaryEnd)
+	}()
+	followed := make(chan error, 1)
+	go func() { followed <- f.Follow(followerEnd) }()
+	return followerEnd, followed
+}
+
+// set sets the memory cell with the name.
+func set(s *stm.STM, name string, a amount) error {
+	tVar, _ := s.Lookup(name)
+	return s.Do(func(t *stm.Transaction) bool {
+		return t.Write(tVar, a)
+	})
+}
+
+// get gives the contents of the memory cell with the name, -1 when there is none.
+func get(s *stm.STM, name string) amount {
+	tVar, ok := s.Lookup(name)
+	if !ok {
+		return -1
+	}
+	a := amount(-1)
+	s.Do(func(t *stm.Transaction) bool {
+		a = t.Read(tVar).(amount)
+		return true
+	})
+	return a
+}
+
+// inStep waits until the replica is at the version of the primary.
+func inStep(t *testing.T, primary, replica *stm.STM) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); replica.Version() != primary.Version(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica at version %d, the primary at %d", replica.Version(), primary.Version())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFollowerCatchesUp(t *testing.T) {
+	primary := stm.New()
+	primary.NewNamedTVar("a", amount(1))
+	set(primary, "a", 2)
+	primary.EnableChangefeed(4) // the commits so far come in a snapshot
+
+	p := NewPrimary(primary, codecs)
+	f := NewFollower(stm.New(), codecs)
+	conn, followed := connect(t, p, f)
+
+	set(primary, "a", 3)
+	primary.NewNamedTVar("b", amount(10))
+	inStep(t, primary, f.STM())
+	if a, b := get(f.STM(), "a"), get(f.STM(), "b"); a != 3 || b != 10 {
+		t.Fatalf("replicated a = %d, b = %d, want 3 and 10", a, b)
+	}
+
+	conn.Close()
+	if err := <-followed; err == nil {
+		t.Fatal("Follow returned no error for a closed connection")
+	}
+	for i := 0; i < 10; i++ {
+		set(primary, "a", amount(100+i))
+	}
+	if _, err := primary.Subscribe(f.STM().Version() + 1); !errors.Is(err, stm.ErrVersionNotRetained) {
+		t.Fatalf("the primary still has the records the follower lacks, error %v", err)
+	}
+
+	connect(t, p, f)
+	inStep(t, primary, f.STM())
+	if a := get(f.STM(), "a"); a != 109 {
+		t.Errorf("replicated a = %d after the snapshot, want 109", a)
+	}
+	set(primary, "a", 110)
+	inStep(t, primary, f.STM())
+	if a := get(f.STM(), "a"); a != 110 {
+		t.Errorf("replicated a = %d after the snapshot and a commit, want 110", a)
+	}
+}
+
+func TestPromotion(t *testing.T) {
+	primary := stm.New()
+	primary.EnableChangefeed(4)
+	primary.NewNamedTVar("a", amount(1))
+
+	f := NewFollower(stm.New(), codecs)
+	_, followed := connect(t, NewPrimary(primary, codecs), f)
+	set(primary, "a", 2)
+	inStep(t, primary, f.STM())
+	if err := set(f.STM(), "a", 3); !errors.Is(err, stm.ErrReadOnly) {
+		t.Fatalf("wrote to the follower, error %v", err)
+	}
+
+	promoted := f.Promote()
+	if err := <-followed; !errors.Is(err, ErrPromoted) {
+		t.Errorf("Follow returned %v after the promotion, want ErrPromoted", err)
+	}
+	if err := f.Follow(nil); !errors.Is(err, ErrPromoted) {
+		t.Errorf("followed again after the promotion, error %v", err)
+	}
+	if err := set(promoted, "a", 42); err != nil {
+		t.Fatalf("wrote to the promoted follower, error %v", err)
+	}
+
+	// the promoted follower is the primary of a new follower
+	promoted.EnableChangefeed(4)
+	f2 := NewFollower(stm.New(), codecs)
+	connect(t, NewPrimary(promoted, codecs), f2)
+	set(promoted, "a", 43)
+	inStep(t, promoted, f2.STM())
+	if a := get(f2.STM(), "a"); a != 43 {
+		t.Errorf("replicated a = %d from the promoted follower, want 43", a)
+	}
+}
+
+func TestPromotionWhileARecordIsApplied(t *testing.T) {
+	primary := stm.New()
+	primary.EnableChangefeed(4)
+	primary.NewNamedTVar("a", amount(1))
+
+	// the changefeed of the follower holds its commits up, Apply waits for the subscription
+	replica := stm.New()
+	replica.EnableChangefeed(1)
+	replica.SetSubscriberTimeout(time.Hour)
+	lagging, _ := replica.Subscribe(1)
+	f := NewFollower(replica, codecs)
+	_, followed := connect(t, NewPrimary(primary, codecs), f)
+	for i := 2; i <= 4; i++ {
+		set(primary, "a", amount(i))
+	}
+	for deadline := time.Now().Add(5 * time.Second); replica.Version() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("replica at version %d, want 2 at least", replica.Version())
+		}
+	}
+
+	promoted := make(chan *stm.STM)
+	go func() { promoted <- f.Promote() }()
+	select {
+	case s := <-promoted:
+		if s.ReadOnly() {
+			t.Error("the promoted STM is read-only")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Promote is held up by the record being applied")
+	}
+
+	lagging.Close()
+	if err := <-followed; !errors.Is(err, ErrPromoted) {
+		t.Errorf("Follow returned %v after the promotion, want ErrPromoted", err)
+	}
+	replicated, _ := replica.Lookup("a")
+	if tVar := replica.NewNamedTVar("a", amount(0)); tVar != replicated || get(replica, "a") < 1 {
+		t.Error("the replicated memory cell wasn't given back by name")
+	}
+}
@@ -0,0 +1,289 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// set.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:38:50 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:57:08 GMT-0700 (PDT)
+//
+
+package stm
+
+import "hash/maphash"
+
+// setBuckets is the number of buckets the elements of a TSet or TMultiset are spread across.
+const setBuckets = 32
+
+// TSet is a transactional set. Its elements are spread across buckets and every bucket
+// is its own memory cell, so transactions adding or removing elements that land in
+// different buckets don't conflict. The buckets are created in a single commit.
+//
+// The elements are hashed with maphash.Comparable, the sets need Go 1.24 or later.
+type TSet[T comparable] struct {
+	bag *tBag[T] // the elements of the set, each with a count of 1
+}
+
+// TMultiset is a transactional multiset, a set where every element has a count of
+// occurrences. It is laid out like a TSet.
+type TMultiset[T comparable] struct {
+	bag *tBag[T] // the elements of the multiset and their counts
+}
+
+// tBag is the bucketed collection of elements and their counts backing the sets.
+type tBag[T comparable] struct {
+	seed    maphash.Seed // the seed for hashing the elements into buckets
+	buckets []TVar       // the buckets, each bucket is a memory cell holding a bagBucket
+}
+
+// bagBucket is a bucket of elements and their counts. It is stored in a memory cell.
+type bagBucket[T comparable] struct {
+	counts map[T]int // the elements in the bucket and their counts, counts are positive
+}
+
+// MakeCopy makes bagBucket conform to the Value interface.
+func (b *bagBucket[T]) MakeCopy() Value {
+	nb := new(bagBucket[T])
+	nb.counts = make(map[T]int, len(b.counts))
+	for elem, count := range b.counts {
+		nb.counts[elem] = count
+	}
+	return nb
+}
+
+// IsEqual checks the equality between two buckets.
+func (b *bagBucket[T]) IsEqual(v Value) bool {
+	vv, ok := v.(*bagBucket[T])
+	if !ok {
+		return false
+	}
+	if len(vv.counts) != len(b.counts) {
+		return false
+	}
+	for elem, count := range b.counts {
+		if vv.counts[elem] != count {
+			return false
+		}
+	}
+	return true
+}
+
+// newTBag creates a new empty bag managed by the STM.
+func newTBag[T comparable](stm *STM) *tBag[T] {
+	bag := new(tBag[T])
+	bag.seed = maphash.MakeSeed()
+	buckets := make([]Value, setBuckets)
+	for i := range buckets {
+		b := new(bagBucket[T])
+		b.counts = make(map[T]int)
+		buckets[i] = b
+	}
+	bag.buckets = stm.newTVars(buckets...) // a single commit for all the buckets
+	return bag
+}
+
+// bucketOf gives the bucket the `elem` belongs to.
+func (bag *tBag[T]) bucketOf(elem T) TVar {
+	return bag.buckets[maphash.Comparable(bag.seed, elem)%uint64(len(bag.buckets))]
+}
+
+// count gives the count of the `elem` in the bag, 0 when it is absent.
+func (bag *tBag[T]) count(t *Transaction, elem T) int {
+	return t.Read(bag.bucketOf(elem)).(*bagBucket[T]).counts[elem]
+}
+
+// setCount sets the count of the `elem` in the bag, a count of 0 or less removes it.
+func (bag *tBag[T]) setCount(t *Transaction, elem T, count int) {
+	bucket := bag.bucketOf(elem)
+	b := t.Read(bucket).(*bagBucket[T])
+	if count <= 0 {
+		delete(b.counts, elem)
+	} else {
+		b.counts[elem] = count
+	}
+	t.Write(bucket, b)
+}
+
+// len gives the sum of the counts of all the elements in the bag.
+func (bag *tBag[T]) len(t *Transaction) int {
+	n := 0
+	for _, bucket := range bag.buckets {
+		for _, count := range t.Read(bucket).(*bagBucket[T]).counts {
+			n += count
+		}
+	}
+	return n
+}
+
+// forEach calls `fn` for every element in the bag and its count, until `fn` returns false.
+// The elements may be modified by `fn`, the iteration is over the buckets as they were read.
+func (bag *tBag[T]) forEach(t *Transaction, fn func(elem T, count int) bool) {
+	for _, bucket := range bag.buckets {
+		for elem, count := range t.Read(bucket).(*bagBucket[T]).counts {
+			if !fn(elem, count) {
+				return
+			}
+		}
+	}
+}
+
+// ------------------------------------------------------------------------
+
+// NewTSet creates a new empty set managed by the STM.
+func NewTSet[T comparable](stm *STM) *TSet[T] {
+	s := new(TSet[T])
+	s.bag = newTBag[T](stm)
+	return s
+}
+
+// Add adds the `elem` to the set. It returns false when the element was already present.
+func (s *TSet[T]) Add(t *Transaction, elem T) bool {
+	if s.bag.count(t, elem) > 0 {
+		return false
+	}
+	s.bag.setCount(t, elem, 1)
+	return true
+}
+
+// Remove removes the `elem` from the set. It returns false when the element was absent.
+func (s *TSet[T]) Remove(t *Transaction, elem T) bool {
+	if s.bag.count(t, elem) == 0 {
+		return false
+	}
+	s.bag.setCount(t, elem, 0)
+	return true
+}
+
+// Contains checks if the `elem` is in the set.
+func (s *TSet[T]) Contains(t *Transaction, elem T) bool {
+	return s.bag.count(t, elem) > 0
+}
+
+// Len gives the number of elements in the set. It reads every bucket of the set.
+func (s *TSet[T]) Len(t *Transaction) int {
+	return s.bag.len(t)
+}
+
+// Union adds all the elements of the `other` set to this set.
+func (s *TSet[T]) Union(t *Transaction, other *TSet[T]) {
+	other.bag.forEach(t, func(elem T, _ int) bool {
+		s.Add(t, elem)
+		return true
+	})
+}
+
+// Intersect removes the elements of this set that are not in the `other` set.
+func (s *TSet[T]) Intersect(t *Transaction, other *TSet[T]) {
+	s.bag.forEach(t, func(elem T, _ int) bool {
+		if !other.Contains(t, elem) {
+			s.bag.setCount(t, elem, 0)
+		}
+		return true
+	})
+}
+
+// ForEach calls `fn` for every element of the set, in no particular order.
+// The iteration stops early when `fn` returns false.
+func (s *TSet[T]) ForEach(t *Transaction, fn func(elem T) bool) {
+	s.bag.forEach(t, func(elem T, _ int) bool {
+		return fn(elem)
+	})
+}
+
+// ------------------------------------------------------------------------
+
+// NewTMultiset creates a new empty multiset managed by the STM.
+func NewTMultiset[T comparable](stm *STM) *TMultiset[T] {
+	ms := new(TMultiset[T])
+	ms.bag = newTBag[T](stm)
+	return ms
+}
+
+// Add adds an occurrence of the `elem` to the multiset and gives its new count.
+func (ms *TMultiset[T]) Add(t *Transaction, elem T) int {
+	count := ms.bag.count(t, elem) + 1
+	ms.bag.setCount(t, elem, count)
+	return count
+}
+
+// Remove removes an occurrence of the `elem` from the multiset. It returns false
+// when the element was absent.
+func (ms *TMultiset[T]) Remove(t *Transaction, elem T) bool {
+	count := ms.bag.count(t, elem)
+	if count == 0 {
+		return false
+	}
+	ms.bag.setCount(t, elem, count-1)
+	return true
+}
+
+// Contains checks if the `elem` occurs at least once in the multiset.
+func (ms *TMultiset[T]) Contains(t *Transaction, elem T) bool {
+	return ms.bag.count(t, elem) > 0
+}
+
+// Count gives the number of occurrences of the `elem` in the multiset.
+func (ms *TMultiset[T]) Count(t *Transaction, elem T) int {
+	return ms.bag.count(t, elem)
+}
+
+// Len gives the total number of occurrences of all the elements in the multiset.
+// It reads every bucket of the multiset.
+func (ms *TMultiset[T]) Len(t *Transaction) int {
+	return ms.bag.len(t)
+}
+
+// Union makes the count of every element the larger of its counts in this and
+// the `other` multiset.
+func (ms *TMultiset[T]) Union(t *Transaction, other *TMultiset[T]) {
+	other.bag.forEach(t, func(elem T, count int) bool {
+		if count > ms.bag.count(t, elem) {
+			ms.bag.setCount(t, elem, count)
+		}
+		return true
+	})
+}
+
+// Intersect makes the count of every element the smaller of its counts in this and
+// the `other` multiset.
+func (ms *TMultiset[T]) Intersect(t *Transaction, other *TMultiset[T]) {
+	ms.bag.forEach(t, func(elem T, count int) bool {
+		if otherCount := other.bag.count(t, elem); otherCount < count {
+			ms.bag.setCount(t, elem, otherCount)
+		}
+		return true
+	})
+}
+
+// ForEach calls `fn` for every element of the multiset and its count, in no particular
+// order. The iteration stops early when `fn` returns false.
+func (ms *TMultiset[T]) ForEach(t *Transaction, fn func(elem T, count int) bool) {
+	ms.bag.forEach(t, fn)
+}
@@ -0,0 +1,243 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// set_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:57:08 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:57:08 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+// elemsOf gives the elements of the set, sorted.
+func elemsOf(s *STM, set *TSet[int]) (elems []int) {
+	s.Do(func(t *Transaction) bool {
+		elems = elems[:0]
+		set.ForEach(t, func(elem int) bool {
+			elems = append(elems, elem)
+			return true
+		})
+		return true
+	})
+	sort.Ints(elems)
+	return elems
+}
+
+func TestNewTSetIsASingleCommit(t *testing.T) {
+	s := New()
+	s.EnableChangefeed(4)
+	before := s.Version()
+	set := NewTSet[int](s)
+	if after := s.Version(); after != before+1 {
+		t.Errorf("NewTSet advanced the version from %d to %d, want a single commit", before, after)
+	}
+
+	sub, err := s.Subscribe(before + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	record, err := sub.Next(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Changes) != len(set.bag.buckets) {
+		t.Errorf("the record of NewTSet has %d changes, want one for each of the %d buckets", len(record.Changes), len(set.bag.buckets))
+	}
+}
+
+func TestTSet(t *testing.T) {
+	s := New()
+	set := NewTSet[int](s)
+
+	s.Do(func(tx *Transaction) bool {
+		if set.Contains(tx, 1) || set.Len(tx) != 0 {
+			t.Error("a new set isn't empty")
+		}
+		if set.Remove(tx, 1) {
+			t.Error("Remove removed an element from an empty set")
+		}
+		for elem := 0; elem < 100; elem++ {
+			if !set.Add(tx, elem) {
+				t.Errorf("Add(%d) found the element already present", elem)
+			}
+		}
+		if set.Add(tx, 7) {
+			t.Error("Add(7) added the element twice")
+		}
+		if !set.Remove(tx, 7) || set.Contains(tx, 7) {
+			t.Error("Remove(7) left the element in the set")
+		}
+		if n := set.Len(tx); n != 99 {
+			t.Errorf("Len: got %d, want 99", n)
+		}
+		return true
+	})
+
+	got := elemsOf(s, set)
+	if len(got) != 99 || got[0] != 0 || got[7] != 8 || got[98] != 99 {
+		t.Errorf("ForEach gave %v, want 0 to 99 without 7", got)
+	}
+}
+
+func TestTSetUnionAndIntersect(t *testing.T) {
+	s := New()
+	a, b := NewTSet[int](s), NewTSet[int](s)
+	s.Do(func(t *Transaction) bool {
+		for _, elem := range []int{1, 2, 3} {
+			a.Add(t, elem)
+		}
+		for _, elem := range []int{2, 3, 4} {
+			b.Add(t, elem)
+		}
+		return true
+	})
+
+	s.Do(func(t *Transaction) bool {
+		a.Union(t, b)
+		return true
+	})
+	if got := elemsOf(s, a); !equalInts(got, []int{1, 2, 3, 4}) {
+		t.Errorf("Union: got %v, want [1 2 3 4]", got)
+	}
+
+	s.Do(func(t *Transaction) bool {
+		b.Remove(t, 4)
+		a.Intersect(t, b)
+		return true
+	})
+	if got := elemsOf(s, a); !equalInts(got, []int{2, 3}) {
+		t.Errorf("Intersect: got %v, want [2 3]", got)
+	}
+}
+
+func TestTSetConcurrentAdds(t *testing.T) {
+	s := New()
+	set := NewTSet[int](s)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				elem := g*50 + i
+				s.Do(func(t *Transaction) bool { return set.Add(t, elem) })
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if got := elemsOf(s, set); len(got) != 200 || got[0] != 0 || got[199] != 199 {
+		t.Errorf("%d elements after the concurrent adds, want 200", len(got))
+	}
+}
+
+func TestTMultiset(t *testing.T) {
+	s := New()
+	ms := NewTMultiset[string](s)
+
+	s.Do(func(tx *Transaction) bool {
+		if ms.Contains(tx, "a") || ms.Count(tx, "a") != 0 || ms.Len(tx) != 0 {
+			t.Error("a new multiset isn't empty")
+		}
+		for i := 1; i <= 3; i++ {
+			if count := ms.Add(tx, "a"); count != i {
+				t.Errorf("Add(a): got the count %d, want %d", count, i)
+			}
+		}
+		ms.Add(tx, "b")
+		if n := ms.Len(tx); n != 4 {
+			t.Errorf("Len: got %d, want 4", n)
+		}
+
+		if !ms.Remove(tx, "b") || ms.Contains(tx, "b") {
+			t.Error("Remove(b) left the only occurrence of b")
+		}
+		if ms.Remove(tx, "b") {
+			t.Error("Remove(b) removed an absent element")
+		}
+		if !ms.Remove(tx, "a") || ms.Count(tx, "a") != 2 {
+			t.Errorf("Remove(a): count %d, want 2", ms.Count(tx, "a"))
+		}
+		return true
+	})
+}
+
+func TestTMultisetUnionAndIntersect(t *testing.T) {
+	s := New()
+	a, b := NewTMultiset[string](s), NewTMultiset[string](s)
+	s.Do(func(t *Transaction) bool {
+		for _, elem := range []string{"x", "x", "y"} {
+			a.Add(t, elem)
+		}
+		for _, elem := range []string{"x", "y", "y", "z"} {
+			b.Add(t, elem)
+		}
+		return true
+	})
+
+	counts := func(ms *TMultiset[string]) map[string]int {
+		counts := make(map[string]int)
+		s.Do(func(t *Transaction) bool {
+			clear(counts)
+			ms.ForEach(t, func(elem string, count int) bool {
+				counts[elem] = count
+				return true
+			})
+			return true
+		})
+		return counts
+	}
+
+	s.Do(func(t *Transaction) bool {
+		a.Union(t, b)
+		return true
+	})
+	if got := counts(a); len(got) != 3 || got["x"] != 2 || got["y"] != 2 || got["z"] != 1 {
+		t.Errorf("Union: got %v, want x:2 y:2 z:1", got)
+	}
+
+	s.Do(func(t *Transaction) bool {
+		b.Remove(t, "x")
+		a.Intersect(t, b)
+		return true
+	})
+	if got := counts(a); len(got) != 2 || got["y"] != 2 || got["z"] != 1 {
+		t.Errorf("Intersect: got %v, want y:2 z:1", got)
+	}
+}
@@ -0,0 +1,214 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// sharded.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:11:54 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:35:37 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// ShardedSTM spreads its memory cells across several STMs, its shards, each with a
+// commit lock of its own. The transactions touching a single shard commit on it alone,
+// in parallel with the transactions of the other shards, and the transactions spanning
+// shards are committed across them, see DoAcross.
+type ShardedSTM struct {
+	shards  []*STM        // the shards
+	lock    *sync.RWMutex // guards the shards of the memory cells
+	shardOf map[TVar]*STM // the shard of every memory cell created by the sharded STM
+	next    uint64        // the number of unnamed memory cells created, they are spread round robin
+}
+
+// ShardedTransaction is a transaction of a ShardedSTM. It reads and writes the memory
+// cells of any shard, like a Transaction does the memory cells of its STM.
+type ShardedTransaction struct {
+	sharded *ShardedSTM       // the sharded STM the transaction is performed on
+	ct      *CrossTransaction // the transaction across the shards touched
+}
+
+// NewSharded makes and initializes a new sharded STM of `n` shards.
+func NewSharded(n int) *ShardedSTM {
+	if n < 1 {
+		n = 1
+	}
+	s := new(ShardedSTM)
+	s.shards = make([]*STM, n)
+	for i := range s.shards {
+		s.shards[i] = New()
+	}
+	s.lock = new(sync.RWMutex)
+	s.shardOf = make(map[TVar]*STM)
+	return s
+}
+
+// NewTVar creates a new memory cell in one of the shards, the unnamed memory cells are
+// spread across the shards round robin, see STM.NewTVar.
+func (s *ShardedSTM) NewTVar(data Value, validators ...Validator) TVar {
+	shard := s.shards[(atomic.AddUint64(&s.next, 1)-1)%uint64(len(s.shards))]
+	return s.register(shard, shard.NewTVar(data, validators...))
+}
+
+// NewNamedTVar creates a new memory cell with a name in the shard of the name, see
+// ShardFor and STM.NewNamedTVar.
+func (s *ShardedSTM) NewNamedTVar(name string, data Value, validators ...Validator) TVar {
+	shard := s.ShardFor(name)
+	return s.register(shard, shard.NewNamedTVar(name, data, validators...))
+}
+
+// register records the shard of the memory cell.
+func (s *ShardedSTM) register(shard *STM, tVar TVar) TVar {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.shardOf[tVar] = shard
+	return tVar
+}
+
+// ShardFor gives the shard of the memory cells with the name, by the hash of the name.
+func (s *ShardedSTM) ShardFor(name string) *STM {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Shard gives the shard of the memory cell created by the sharded STM. The last result
+// is false for the memory cells of other STMs.
+func (s *ShardedSTM) Shard(tVar TVar) (*STM, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	shard, ok := s.shardOf[tVar]
+	return shard, ok
+}
+
+// Shards gives the shards of the sharded STM, e.g. for their statistics.
+func (s *ShardedSTM) Shards() []*STM {
+	return append([]*STM(nil), s.shards...)
+}
+
+// Do performs the transactional action and waits for it to complete, like STM.Do. The
+// transaction commits on the shard it touches, or across the shards when it touches
+// several. It returns the error the transaction was aborted with, nil when it has
+// committed.
+func (s *ShardedSTM) Do(action func(*ShardedTransaction) bool) error {
+	return s.DoContext(context.Background(), action)
+}
+
+// DoLabelled is Do for a transaction with a label, see STM.PerformLabelled.
+func (s *ShardedSTM) DoLabelled(label string, action func(*ShardedTransaction) bool) error {
+	return s.DoContext(ContextWithLabel(context.Background(), label), action)
+}
+
+// DoContext is Do with a context, see STM.DoContext.
+func (s *ShardedSTM) DoContext(ctx context.Context, action func(*ShardedTransaction) bool) error {
+	return DoAcrossContext(ctx, func(ct *CrossTransaction) bool {
+		return action(&ShardedTransaction{sharded: s, ct: ct})
+	})
+}
+
+// PrintState logs the current state of the memory cells of every shard, their snapshots
+// as JSON. The snapshots are consistent across the shards, see Snapshots.
+func (s *ShardedSTM) PrintState() {
+	for i, snap := range s.Snapshots() {
+		log.Printf("shard %d:", i)
+		printSnapshot(snap)
+	}
+}
+
+// Snapshots takes the snapshots of all the shards, in the order of Shards, see
+// STM.Snapshot. It holds the commit locks of all the shards while taking them, so they
+// are consistent across the shards, like the state a transaction across all of them
+// reads.
+func (s *ShardedSTM) Snapshots() []*Snapshot {
+	// the shards are in the order of their serial numbers, the commit locks are acquired
+	// in the order DoAcross acquires them in
+	for _, shard := range s.shards {
+		shard.acquireCommitLock()
+	}
+	defer func() {
+		for i := len(s.shards) - 1; i >= 0; i-- {
+			s.shards[i].releaseCommitLock()
+		}
+	}()
+
+	snaps := make([]*Snapshot, len(s.shards))
+	for i, shard := range s.shards {
+		snaps[i] = shard.snapshot()
+	}
+	return snaps
+}
+
+// Read reads the contents of the memory cell referenced by the `tVar`, see Transaction.Read.
+func (st *ShardedTransaction) Read(tVar TVar) Value {
+	return st.On(tVar).Read(tVar)
+}
+
+// Write writes the new contents of the memory cell referenced by the `tVar`, see
+// Transaction.Write.
+func (st *ShardedTransaction) Write(tVar TVar, newData Value) bool {
+	return st.On(tVar).Write(tVar, newData)
+}
+
+// Commute records a commutative update of the memory cell referenced by the `tVar`, see
+// Transaction.Commute.
+func (st *ShardedTransaction) Commute(tVar TVar, fn func(Value) Value) bool {
+	return st.On(tVar).Commute(tVar, fn)
+}
+
+// Retry abandons the current attempt of the transaction until another transaction
+// changes one of the memory cells it has read, see Transaction.Retry.
+func (st *ShardedTransaction) Retry() {
+	panic(retrySignal{})
+}
+
+// Attempt gives the number of the current attempt of the transaction, starting at 1.
+func (st *ShardedTransaction) Attempt() int {
+	return st.ct.Attempt()
+}
+
+// On gives the part of the transaction on the shard of the memory cell, for the
+// functions taking the *Transaction of the memory cell. It panics for the memory cells
+// of other STMs.
+func (st *ShardedTransaction) On(tVar TVar) *Transaction {
+	shard, ok := st.sharded.Shard(tVar)
+	if !ok {
+		panic(fmt.Sprintf("stm: memory cell %s is not in the sharded STM", tVar.(*memoryCell).label()))
+	}
+	return st.ct.On(shard)
+}
@@ -0,0 +1,202 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// sharded_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:39:21 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:35:37 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// shardIndex gives the index of the shard in the shards of the sharded STM.
+func shardIndex(s *ShardedSTM, shard *STM) int {
+	for i, sh := range s.Shards() {
+		if sh == shard {
+			return i
+		}
+	}
+	return -1
+}
+
+// commits gives the number of commits of every shard.
+func commits(s *ShardedSTM) []uint64 {
+	shards := s.Shards()
+	n := make([]uint64, len(shards))
+	for i, shard := range shards {
+		n[i] = shard.Stats().Commits
+	}
+	return n
+}
+
+func TestShardedSnapshots(t *testing.T) {
+	s := NewSharded(3)
+	want := make([]map[string]counterValue, 3)
+	for i := range want {
+		want[i] = make(map[string]counterValue)
+	}
+	for i := 0; i < 9; i++ {
+		name := fmt.Sprintf("account-%d", i)
+		tVar := s.NewNamedTVar(name, counterValue(i))
+		shard, ok := s.Shard(tVar)
+		if !ok || shard != s.ShardFor(name) {
+			t.Fatalf("%s is in shard %d, want shard %d", name, shardIndex(s, shard), shardIndex(s, s.ShardFor(name)))
+		}
+		want[shardIndex(s, shard)][name] = counterValue(i)
+	}
+	unnamed := s.NewTVar(counterValue(100))
+	shard, _ := s.Shard(unnamed)
+
+	snaps := s.Snapshots()
+	if len(snaps) != 3 {
+		t.Fatalf("%d snapshots, want one for each of the 3 shards", len(snaps))
+	}
+	for i, snap := range snaps {
+		got := make(map[string]counterValue)
+		for _, cell := range snap.Cells {
+			if cell.Name == "" {
+				if i != shardIndex(s, shard) || cell.ID != unnamed.(*memoryCell).id || cell.Value != counterValue(100) {
+					t.Errorf("shard %d holds the unnamed memory cell %s holding %v", i, cell.ID, cell.Value)
+				}
+				continue
+			}
+			got[cell.Name] = cell.Value.(counterValue)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want[i]) {
+			t.Errorf("shard %d holds %v, want %v", i, got, want[i])
+		}
+		if snap.Version != s.Shards()[i].Version() {
+			t.Errorf("the snapshot of shard %d is at version %d, want %d", i, snap.Version, s.Shards()[i].Version())
+		}
+	}
+}
+
+func TestShardedTransactionsCommitOnTheShardsTouched(t *testing.T) {
+	s := NewSharded(2)
+	var a, b, c TVar // a and b in the same shard, c in the other one
+	for i := 0; b == nil || c == nil; i++ {
+		name := fmt.Sprintf("cell-%d", i)
+		switch {
+		case a == nil:
+			a = s.NewNamedTVar(name, counterValue(0))
+		case s.ShardFor(name) == s.ShardFor("cell-0") && b == nil:
+			b = s.NewNamedTVar(name, counterValue(0))
+		case s.ShardFor(name) != s.ShardFor("cell-0") && c == nil:
+			c = s.NewNamedTVar(name, counterValue(0))
+		}
+	}
+	first, _ := s.Shard(a)
+	i, j := shardIndex(s, first), 1-shardIndex(s, first)
+
+	before := commits(s)
+	s.Do(func(st *ShardedTransaction) bool {
+		st.Write(a, counterValue(1))
+		return st.Write(b, counterValue(1))
+	})
+	after := commits(s)
+	if after[i] != before[i]+1 || after[j] != before[j] {
+		t.Errorf("a single shard transaction made commits %v -> %v, want one on shard %d alone", before, after, i)
+	}
+
+	before = after
+	s.Do(func(st *ShardedTransaction) bool {
+		st.Write(a, counterValue(2))
+		return st.Write(c, counterValue(2))
+	})
+	after = commits(s)
+	if after[i] != before[i]+1 || after[j] != before[j]+1 {
+		t.Errorf("a cross shard transaction made commits %v -> %v, want one on each shard", before, after)
+	}
+}
+
+func TestShardedTransactionPanicsForAForeignTVar(t *testing.T) {
+	s := NewSharded(2)
+	foreign := New().NewTVar(counterValue(0))
+	if _, ok := s.Shard(foreign); ok {
+		t.Error("the memory cell of another STM is in a shard")
+	}
+	s.Do(func(st *ShardedTransaction) bool {
+		expectPanic(t, "Read", func() { st.Read(foreign) })
+		expectPanic(t, "Write", func() { st.Write(foreign, counterValue(1)) })
+		return true
+	})
+}
+
+func TestShardedSnapshotsAreConsistent(t *testing.T) {
+	const accounts, initial = 6, 100
+	s := NewSharded(3)
+	balances := make([]TVar, accounts)
+	for i := range balances {
+		balances[i] = s.NewNamedTVar(fmt.Sprintf("account-%d", i), counterValue(initial))
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				from, to := balances[rand.Intn(accounts)], balances[rand.Intn(accounts)]
+				s.Do(func(st *ShardedTransaction) bool {
+					st.Write(from, st.Read(from).(counterValue)-1)
+					return st.Write(to, st.Read(to).(counterValue)+1)
+				})
+			}
+		}()
+	}
+
+	for n := 0; n < 200; n++ {
+		total := counterValue(0)
+		for _, snap := range s.Snapshots() {
+			for _, cell := range snap.Cells {
+				total += cell.Value.(counterValue)
+			}
+		}
+		if total != accounts*initial {
+			t.Errorf("the snapshots hold a total of %d, want %d", total, accounts*initial)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,138 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// snapshot.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:48:59 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:21:15 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Snapshot is a consistent point-in-time view of all the memory cells of an STM.
+type Snapshot struct {
+	Version uint64         `json:"version"` // the version of the STM the snapshot was taken at
+	Cells   []CellSnapshot `json:"cells"`   // the memory cells, in the order they were created
+}
+
+// CellSnapshot is the view of a single memory cell in a Snapshot.
+type CellSnapshot struct {
+	ID      string `json:"id"`             // the ID of the memory cell
+	Name    string `json:"name,omitempty"` // the name of the memory cell, empty if none
+	Version uint64 `json:"version"`        // the version of the STM the memory cell was last written at
+	Value   Value  `json:"-"`              // a copy of the contents of the memory cell
+}
+
+// Snapshot takes a snapshot of all the memory cells of the STM, computed TVars aside. It is
+// taken under the commit lock, so it reflects all the commits up to its version and none
+// after it.
+func (stm *STM) Snapshot() *Snapshot {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	return stm.snapshot()
+}
+
+// snapshot takes a snapshot of all the memory cells. The caller must hold the commit lock.
+func (stm *STM) snapshot() *Snapshot {
+	stm.memoryLock.RLock()
+	defer stm.memoryLock.RUnlock()
+
+	snap := new(Snapshot)
+	snap.Version = stm.version
+	snap.Cells = make([]CellSnapshot, 0, len(stm.memory))
+	for _, memCell := range stm.memory {
+		if memCell.compute != nil {
+			continue // computed TVars are derived from the other memory cells
+		}
+		snap.Cells = append(snap.Cells, CellSnapshot{
+			ID:      memCell.id,
+			Name:    memCell.name,
+			Version: memCell.lastVersion,
+			Value:   memCell.read(),
+		})
+	}
+	return snap
+}
+
+// MarshalJSON makes CellSnapshot conform to the json.Marshaler interface. Values that
+// are json.Marshalers are encoded as JSON. Other values are encoded with DefaultCodecs,
+// as the type and the base64 of the data the registry encodes them to, so that they
+// can be decoded back. Values without a codec are encoded as their %v string.
+func (cell CellSnapshot) MarshalJSON() ([]byte, error) {
+	type cellSnapshot CellSnapshot // without the MarshalJSON method
+	encoded := struct {
+		cellSnapshot
+		Type  string      `json:"type,omitempty"`
+		Data  []byte      `json:"data,omitempty"`
+		Value interface{} `json:"value,omitempty"`
+	}{cellSnapshot: cellSnapshot(cell)}
+
+	if m, ok := cell.Value.(json.Marshaler); ok {
+		encoded.Value = m
+		return json.Marshal(encoded)
+	}
+	typ, data, err := DefaultCodecs.Encode(cell.Value)
+	switch {
+	case err == nil:
+		encoded.Type, encoded.Data = typ, data
+	case errors.Is(err, ErrNoCodec):
+		encoded.Value = fmt.Sprintf("%v", cell.Value)
+	default:
+		return nil, fmt.Errorf("stm: encoding memory cell %s: %w", cell.ID, err)
+	}
+	return json.Marshal(encoded)
+}
+
+// WriteJSON writes the snapshot to `w` as indented JSON.
+func (snap *Snapshot) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+// WriteTable writes the snapshot to `w` as a table for humans, a row per memory cell.
+func (snap *Snapshot) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "STM at version %d\n", snap.Version)
+	fmt.Fprintln(tw, "ID\tNAME\tVERSION\tVALUE")
+	for _, cell := range snap.Cells {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%v\n", cell.ID, cell.Name, cell.Version, cell.Value)
+	}
+	return tw.Flush()
+}
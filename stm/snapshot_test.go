@@ -0,0 +1,193 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// snapshot_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:21:15 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:21:15 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+// label is a value encoding itself as JSON.
+type label string
+
+func (l label) MakeCopy() Value          { return l }
+func (l label) IsEqual(other Value) bool { return other == l }
+func (l label) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"label": string(l)})
+}
+
+// opaque is a value without a codec.
+type opaque struct{ n int }
+
+func (o opaque) MakeCopy() Value          { return o }
+func (o opaque) IsEqual(other Value) bool { return other == o }
+
+// decodedCell is a memory cell of a snapshot decoded from its JSON.
+type decodedCell struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Version uint64          `json:"version"`
+	Type    string          `json:"type"`
+	Data    []byte          `json:"data"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// decodeSnapshot decodes the JSON of the snapshot, by the IDs of the memory cells.
+func decodeSnapshot(t *testing.T, snap *Snapshot) (uint64, map[string]decodedCell) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := snap.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Version uint64        `json:"version"`
+		Cells   []decodedCell `json:"cells"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("the snapshot's JSON doesn't decode: %v\n%s", err, buf.String())
+	}
+	cells := make(map[string]decodedCell, len(decoded.Cells))
+	for _, cell := range decoded.Cells {
+		cells[cell.ID] = cell
+	}
+	return decoded.Version, cells
+}
+
+// diff gives the IDs of the memory cells that differ between the decoded snapshots.
+func diff(before, after map[string]decodedCell) (changed []string) {
+	for id, cell := range after {
+		old, ok := before[id]
+		if !ok || old.Version != cell.Version || !bytes.Equal(old.Data, cell.Data) || !bytes.Equal(old.Value, cell.Value) {
+			changed = append(changed, id)
+		}
+	}
+	return changed
+}
+
+func TestSnapshotsDiff(t *testing.T) {
+	s := New()
+	a := s.NewNamedTVar("a", counterValue(1))
+	b := s.NewNamedTVar("b", counterValue(2))
+	_, before := decodeSnapshot(t, s.Snapshot())
+
+	set(s, b, counterValue(3))
+	c := s.NewTVar(counterValue(4))
+	version, after := decodeSnapshot(t, s.Snapshot())
+
+	if version != s.Version() {
+		t.Errorf("snapshot at version %d, want %d", version, s.Version())
+	}
+	changed := diff(before, after)
+	if len(changed) != 2 {
+		t.Fatalf("the snapshots differ in %v, want b and the new memory cell", changed)
+	}
+	for _, id := range changed {
+		if id != b.(*memoryCell).id && id != c.(*memoryCell).id {
+			t.Errorf("memory cell %s changed, want b and the new memory cell only", id)
+		}
+	}
+	if cell := after[a.(*memoryCell).id]; cell.Name != "a" || cell.Version != before[cell.ID].Version {
+		t.Errorf("memory cell a is %+v in the second snapshot, want it unchanged", cell)
+	}
+}
+
+func TestSnapshotJSONEncodesTheValues(t *testing.T) {
+	s := New()
+	counter := s.NewTVar(counterValue(42))
+	marshaler := s.NewTVar(label("x"))
+	other := s.NewTVar(opaque{7})
+	_, cells := decodeSnapshot(t, s.Snapshot())
+
+	cell := cells[counter.(*memoryCell).id]
+	if cell.Type != "stm.counter" {
+		t.Fatalf("the counter is encoded as %q, want the codec's type", cell.Type)
+	}
+	if v, err := DefaultCodecs.Decode(cell.Type, cell.Data); err != nil || v != counterValue(42) {
+		t.Errorf("the counter decodes to %v, %v, want 42", v, err)
+	}
+	var compact bytes.Buffer
+	json.Compact(&compact, cells[marshaler.(*memoryCell).id].Value)
+	if got := compact.String(); got != `{"label":"x"}` {
+		t.Errorf("the json.Marshaler is encoded as %s", got)
+	}
+	if got := string(cells[other.(*memoryCell).id].Value); got != `"{7}"` {
+		t.Errorf("the value without a codec is encoded as %s, want its %%v string", got)
+	}
+}
+
+func TestSnapshotWriteTable(t *testing.T) {
+	s := New()
+	s.NewNamedTVar("balance", counterValue(42))
+	var buf bytes.Buffer
+	if err := s.Snapshot().WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[0] != "STM at version 1" || !strings.HasPrefix(lines[1], "ID") {
+		t.Fatalf("the table is\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 4 || fields[1] != "balance" || fields[2] != "1" || fields[3] != "42" {
+		t.Errorf("the row of the memory cell is %q", lines[2])
+	}
+}
+
+func TestPrintStateLogsTheSnapshot(t *testing.T) {
+	s := New()
+	s.NewNamedTVar("balance", counterValue(42))
+
+	var out bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	s.PrintState()
+
+	var printed struct {
+		Version uint64        `json:"version"`
+		Cells   []decodedCell `json:"cells"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("PrintState printed something else than JSON: %v\n%s", err, out.String())
+	}
+	if printed.Version != 1 || len(printed.Cells) != 1 || printed.Cells[0].Name != "balance" {
+		t.Errorf("PrintState printed\n%s", out.String())
+	}
+}
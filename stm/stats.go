@@ -0,0 +1,216 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// stats.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:45:39 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:48:32 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// AbortReason is the reason an attempt of a transaction was rolled back.
+type AbortReason int
+
+const (
+	// AbortConflict is for a commit that conflicted with other transactions.
+	AbortConflict AbortReason = iota
+	// AbortInvalid is for a commit rejected by a validator or an invariant.
+	AbortInvalid
+	// AbortFailed is for an action that returned false.
+	AbortFailed
+	// AbortRetry is for an action that called Transaction.Retry.
+	AbortRetry
+
+	abortReasons // the number of reasons
+)
+
+// String gives the name of the abort reason.
+func (r AbortReason) String() string {
+	switch r {
+	case AbortConflict:
+		return "conflict"
+	case AbortInvalid:
+		return "invalid"
+	case AbortFailed:
+		return "failed"
+	case AbortRetry:
+		return "retry"
+	}
+	return "unknown"
+}
+
+// Stats is a snapshot of the statistics of the transactions performed by an STM.
+type Stats struct {
+	Transactions   uint64                 // the number of transactions started
+	Active         int64                  // the number of transactions running right now
+	Commits        uint64                 // the number of transactions committed
+	Failures       uint64                 // the number of transactions given up on without committing
+	Retries        uint64                 // the number of attempts after the first, over all the transactions
+	Aborts         map[AbortReason]uint64 // the number of attempts rolled back, by reason
+	CommitLockWait time.Duration          // the total time spent waiting for the commit lock
+	ReadSetSize    uint64                 // the total size of the read sets of the committed transactions
+	WriteSetSize   uint64                 // the total size of the write sets of the committed transactions
+}
+
+// TransactionReport describes a completed transaction to a metrics sink.
+type TransactionReport struct {
+	ID           uint64        // the ID of the transaction
+	Label        string        // the label of the transaction, empty if none
+	Attempts     int           // the number of attempts, the first one included
+	ReadSetSize  int           // the size of the read set, 0 when it didn't commit
+	WriteSetSize int           // the size of the write set, 0 when it didn't commit
+	Duration     time.Duration // the time from the start of the first attempt to completion
+	Err          error         // the error the transaction was aborted with, nil when it committed
+}
+
+// MetricsSink receives the events of the transactions performed by an STM as they happen.
+// The methods are called from the goroutines running the transactions, so they must be
+// safe for concurrent use, and quick.
+type MetricsSink interface {
+	// TransactionStarted is called when a transaction starts.
+	TransactionStarted(id uint64)
+	// AttemptAborted is called when an attempt of a transaction is rolled back. The error is
+	// the *ConflictError for AbortConflict and the validation error for AbortInvalid.
+	AttemptAborted(id uint64, reason AbortReason, err error)
+	// CommitLockWaited is called with the time a commit waited for the commit lock.
+	CommitLockWaited(wait time.Duration)
+	// TransactionCompleted is called when a transaction has committed or has been given up on.
+	TransactionCompleted(report *TransactionReport)
+}
+
+// stats are the counters behind Stats, updated atomically.
+type stats struct {
+	transactions   uint64
+	active         int64
+	commits        uint64
+	failures       uint64
+	retries        uint64
+	aborts         [abortReasons]uint64
+	commitLockWait int64
+	readSetSize    uint64
+	writeSetSize   uint64
+}
+
+// metricsSinkHolder holds the metrics sink of an STM in an atomic.Value.
+type metricsSinkHolder struct {
+	sink MetricsSink
+}
+
+// Stats gives a snapshot of the statistics of the transactions performed by the STM.
+// The counters are read one by one, they may be off by the transactions in flight.
+func (stm *STM) Stats() Stats {
+	s := Stats{
+		Transactions:   atomic.LoadUint64(&stm.stats.transactions),
+		Active:         atomic.LoadInt64(&stm.stats.active),
+		Commits:        atomic.LoadUint64(&stm.stats.commits),
+		Failures:       atomic.LoadUint64(&stm.stats.failures),
+		Retries:        atomic.LoadUint64(&stm.stats.retries),
+		Aborts:         make(map[AbortReason]uint64, abortReasons),
+		CommitLockWait: time.Duration(atomic.LoadInt64(&stm.stats.commitLockWait)),
+		ReadSetSize:    atomic.LoadUint64(&stm.stats.readSetSize),
+		WriteSetSize:   atomic.LoadUint64(&stm.stats.writeSetSize),
+	}
+	for reason := AbortReason(0); reason < abortReasons; reason++ {
+		s.Aborts[reason] = atomic.LoadUint64(&stm.stats.aborts[reason])
+	}
+	return s
+}
+
+// SetMetricsSink sets the sink receiving the events of the transactions, nil removes it.
+func (stm *STM) SetMetricsSink(sink MetricsSink) {
+	stm.metrics.Store(metricsSinkHolder{sink: sink})
+}
+
+// metricsSink gives the metrics sink of the STM, nil when there is none.
+func (stm *STM) metricsSink() MetricsSink {
+	holder, _ := stm.metrics.Load().(metricsSinkHolder)
+	return holder.sink
+}
+
+// beginTransaction records the start of the transaction.
+func (stm *STM) beginTransaction(t *Transaction) {
+	t.started = time.Now()
+	atomic.AddUint64(&stm.stats.transactions, 1)
+	atomic.AddInt64(&stm.stats.active, 1)
+	if sink := stm.metricsSink(); sink != nil {
+		sink.TransactionStarted(t.id)
+	}
+	stm.startSpan(t)
+}
+
+// abortAttempt records the rollback of the current attempt of the transaction.
+func (stm *STM) abortAttempt(t *Transaction, reason AbortReason, err error) {
+	atomic.AddUint64(&stm.stats.aborts[reason], 1)
+	if sink := stm.metricsSink(); sink != nil {
+		sink.AttemptAborted(t.id, reason, err)
+	}
+	t.traceAbort(reason, err)
+}
+
+// commitLockWaited records the time a commit waited for the commit lock.
+func (stm *STM) commitLockWaited(wait time.Duration) {
+	atomic.AddInt64(&stm.stats.commitLockWait, int64(wait))
+	if sink := stm.metricsSink(); sink != nil {
+		sink.CommitLockWaited(wait)
+	}
+}
+
+// endTransaction records the completion of the transaction.
+func (stm *STM) endTransaction(t *Transaction) {
+	atomic.AddInt64(&stm.stats.active, -1)
+	atomic.AddUint64(&stm.stats.retries, uint64(t.attempts-1))
+	if t.err != nil {
+		atomic.AddUint64(&stm.stats.failures, 1)
+	} else {
+		atomic.AddUint64(&stm.stats.commits, 1)
+		atomic.AddUint64(&stm.stats.readSetSize, uint64(t.readSetSize))
+		atomic.AddUint64(&stm.stats.writeSetSize, uint64(t.writeSetSize))
+	}
+
+	if sink := stm.metricsSink(); sink != nil {
+		report := new(TransactionReport)
+		report.ID = t.id
+		report.Label = t.label
+		report.Attempts = t.attempts
+		report.ReadSetSize = t.readSetSize
+		report.WriteSetSize = t.writeSetSize
+		report.Duration = time.Since(t.started)
+		report.Err = t.err
+		sink.TransactionCompleted(report)
+	}
+	t.endSpan()
+}
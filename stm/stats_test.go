@@ -0,0 +1,139 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// stats_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:15:10 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:15:10 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reports is a metrics sink keeping the reports of the completed transactions.
+type reports struct {
+	sync.Mutex
+	completed []*TransactionReport
+}
+
+func (r *reports) TransactionStarted(id uint64)                            {}
+func (r *reports) AttemptAborted(id uint64, reason AbortReason, err error) {}
+func (r *reports) CommitLockWaited(wait time.Duration)                     {}
+
+func (r *reports) TransactionCompleted(report *TransactionReport) {
+	r.Lock()
+	defer r.Unlock()
+	r.completed = append(r.completed, report)
+}
+
+// mustPanic calls fn and checks it panicked.
+func mustPanic(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Error("the action's panic didn't go on unwinding")
+		}
+	}()
+	fn()
+}
+
+func TestStatsCountTheCommitsAndTheAborts(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(0), nonNegative)
+	sink := new(reports)
+	s.SetMetricsSink(sink)
+
+	s.DoLabelled("commit", func(t *Transaction) bool { return t.Write(tVar, counterValue(1)) })
+	s.Do(func(t *Transaction) bool { return t.Write(tVar, counterValue(-1)) })
+
+	stats := s.Stats()
+	if stats.Transactions != 2 || stats.Commits != 1 || stats.Failures != 1 || stats.Active != 0 {
+		t.Errorf("%d transactions, %d commits, %d failures, %d active, want 2, 1, 1 and 0",
+			stats.Transactions, stats.Commits, stats.Failures, stats.Active)
+	}
+	if stats.Aborts[AbortInvalid] != 1 || stats.WriteSetSize != 1 {
+		t.Errorf("%d invalid attempts, write set size %d, want 1 and 1", stats.Aborts[AbortInvalid], stats.WriteSetSize)
+	}
+	if len(sink.completed) != 2 || sink.completed[0].Label != "commit" || sink.completed[0].Err != nil || sink.completed[1].Err == nil {
+		t.Errorf("the sink received %d reports, want the labelled commit and the failure", len(sink.completed))
+	}
+}
+
+func TestStatsCountAPanickingActionAsAFailure(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(0))
+	sink := new(reports)
+	s.SetMetricsSink(sink)
+
+	mustPanic(t, func() {
+		s.Do(func(t *Transaction) bool {
+			t.Write(tVar, counterValue(1))
+			panic("boom")
+		})
+	})
+
+	stats := s.Stats()
+	if stats.Commits != 0 || stats.Failures != 1 || stats.Aborts[AbortFailed] != 1 || stats.Active != 0 {
+		t.Errorf("%d commits, %d failures, %d failed attempts, %d active, want 0, 1, 1 and 0",
+			stats.Commits, stats.Failures, stats.Aborts[AbortFailed], stats.Active)
+	}
+	if len(sink.completed) != 1 || !errors.Is(sink.completed[0].Err, ErrActionPanicked) {
+		t.Errorf("the sink received %d reports, want one with ErrActionPanicked", len(sink.completed))
+	}
+	if countOf(tVar) != 0 {
+		t.Error("the write of the action that panicked was committed")
+	}
+}
+
+func TestStatsCountAPanickingCrossTransactionAsAFailure(t *testing.T) {
+	s1, s2 := New(), New()
+	a, b := s1.NewTVar(counterValue(0)), s2.NewTVar(counterValue(0))
+
+	mustPanic(t, func() {
+		DoAcross(func(ct *CrossTransaction) bool {
+			ct.On(s1).Write(a, counterValue(1))
+			ct.On(s2).Write(b, counterValue(1))
+			panic("boom")
+		})
+	})
+
+	for _, s := range []*STM{s1, s2} {
+		if stats := s.Stats(); stats.Commits != 0 || stats.Failures != 1 || stats.Active != 0 {
+			t.Errorf("%d commits, %d failures, %d active, want 0, 1 and 0", stats.Commits, stats.Failures, stats.Active)
+		}
+	}
+}
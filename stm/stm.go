@@ -32,38 +32,206 @@
 // stm.go
 // @author Sidharth Mishra
 // @created Thu Mar 29 2018 00:22:14 GMT-0700 (PDT)
-// @last-modified Sat Apr 14 2018 11:00:55 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
 //
 
 package stm
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 )
 
 // STM is the single shared memory store that can only be modified by transactions.
 type STM struct {
-	memory     []*memoryCell // the collection of memory cells makes up the memory
-	commitLock *sync.Mutex   // the commit lock needed for maintaining consistency -- serializability
+	serial       uint64                               // the serial number of the STM, the order the commit locks of several STMs are acquired in
+	memory       []*memoryCell                        // the collection of memory cells makes up the memory
+	memoryLock   *sync.RWMutex                        // guards the collection of memory cells
+	commitLock   *sync.Mutex                          // the commit lock needed for maintaining consistency -- serializability
+	commitCond   *sync.Cond                           // signalled after every commit, used by the retrying transactions
+	version      uint64                               // the number of commits so far, guarded by the commit lock
+	watchers     map[*memoryCell][]*Watcher           // the watchers of the memory cells
+	watchLock    *sync.RWMutex                        // guards the watchers
+	dependents   map[*memoryCell]map[*memoryCell]bool // the computed TVars cached from each memory cell, guarded by the commit lock
+	invariants   []*invariant                         // the invariants checked by every commit, guarded by the commit lock
+	contention   ContentionManager                    // decides the fate of the conflicting transactions, guarded by the commit lock
+	transactions uint64                               // the number of transactions made so far, used for their IDs
+	stats        *stats                               // the statistics of the transactions
+	metrics      atomic.Value                         // the metricsSinkHolder of the metrics sink, if any
+	tracer       atomic.Value                         // the tracerHolder of the tracer, if any
+	wal          *wal                                 // the write-ahead log of a persisted STM, guarded by the commit lock
+	names        map[string]*memoryCell               // the named memory cells by name, guarded by the commit lock
+	unclaimed    map[string]*memoryCell               // the recovered memory cells yet to be reclaimed by name, guarded by the commit lock
+	retention    uint64                               // the number of past versions retained for the time-travel reads, guarded by the commit lock
+	retainedFrom uint64                               // the version the past versions are retained from, guarded by the commit lock
+	pins         map[uint64]int                       // the versions being read by the time-travel reads, guarded by the commit lock
+	feed         *changefeed                          // the changefeed of the commits
+	readOnly     bool                                 // only Apply changes the memory cells, guarded by the commit lock
 }
 
+// stmSerials is the number of STMs made so far, used for their serial numbers.
+var stmSerials uint64
+
 // New makes and initializes a new STM instance.
 func New() (stm *STM) {
 	stm = new(STM)
+	stm.serial = atomic.AddUint64(&stmSerials, 1)
 	stm.memory = make([]*memoryCell, 0, 0)
+	stm.memoryLock = new(sync.RWMutex)
 	stm.commitLock = new(sync.Mutex)
+	stm.commitCond = sync.NewCond(stm.commitLock)
+	stm.watchers = make(map[*memoryCell][]*Watcher)
+	stm.watchLock = new(sync.RWMutex)
+	stm.dependents = make(map[*memoryCell]map[*memoryCell]bool)
+	stm.names = make(map[string]*memoryCell)
+	stm.unclaimed = make(map[string]*memoryCell)
+	stm.stats = new(stats)
+	stm.pins = make(map[uint64]int)
+	stm.feed = newChangefeed()
 	return stm
 }
 
 // NewTVar creates a new memory cell in the STM and returns the reference
-// to the memory cell as a TVar instance.
-func (stm *STM) NewTVar(data Value) TVar {
-	memCell := newMemCell(data)
-	stm.memory = append(stm.memory, memCell)
+// to the memory cell as a TVar instance. The `validators` are checked against
+// every new value a transaction commits into the memory cell, a rejected value
+// aborts the transaction. The initial data is not checked.
+func (stm *STM) NewTVar(data Value, validators ...Validator) TVar {
+	return stm.NewNamedTVar("", data, validators...)
+}
+
+// NewNamedTVar is NewTVar for a memory cell with a name. The name is used in place
+// of the memory cell's ID in PrintState, errors, metrics and traces, and the memory
+// cell can be looked up by it, see Lookup. The names are unique, it panics with an
+// error wrapping ErrDuplicateName when the STM already has a memory cell with the name.
+// Creating the memory cell is a commit of its own, it advances the version of the STM.
+// On a persisted STM it panics with the error of the write-ahead log when the memory
+// cell can't be logged.
+//
+// On a persisted STM the memory cell recovered from the write-ahead log with the name
+// is given back instead, with its recovered contents, see Open. On a read-only STM the
+// replicated memory cell with the name is given back, see SetReadOnly, and it panics
+// with an error wrapping ErrReadOnly when there is none: a read-only STM only holds
+// the memory cells of the STM it replicates.
+func (stm *STM) NewNamedTVar(name string, data Value, validators ...Validator) TVar {
+	return stm.newTVar(name, func(TVar) Value { return data }, validators...)
+}
+
+// ErrDuplicateName is the error of creating a memory cell with the name of another
+// memory cell of the STM, see NewNamedTVar.
+var ErrDuplicateName = errors.New("stm: the name is taken by another memory cell")
+
+// newTVar is NewNamedTVar for the contents made by `makeData`, given the TVar of the new
+// memory cell, for the contents referencing their own memory cell, e.g. the root of a
+// TList. The contents are complete before the memory cell is shared.
+func (stm *STM) newTVar(name string, makeData func(TVar) Value, validators ...Validator) TVar {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if memCell, ok := stm.reclaim(name); ok {
+		memCell.validators = validators
+		return TVar(memCell)
+	}
+	if _, ok := stm.lookup(name); ok {
+		panic(fmt.Errorf("stm: creating memory cell %q: %w", name, ErrDuplicateName))
+	}
+
+	memCell := newMemCell(stm, nil)
+	memCell.data = makeData(memCell)
+	memCell.name = name
+	memCell.validators = validators
+
+	if stm.readOnly {
+		// a memory cell of the replica alone would never be changed by its primary
+		panic(fmt.Errorf("stm: creating memory cell %q: %w, only the replicated memory cells can be referenced", name, ErrReadOnly))
+	}
+
+	stm.create(memCell)
 	return TVar(memCell)
 }
 
+// newTVars creates unnamed memory cells holding the `values` in a single commit, for
+// the containers made of many memory cells, e.g. the buckets of a TSet. It advances the
+// version of the STM once, there is a single log record and change record for them all.
+func (stm *STM) newTVars(values ...Value) []TVar {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if stm.readOnly {
+		panic(fmt.Errorf("stm: creating memory cells: %w, only the replicated memory cells can be referenced", ErrReadOnly))
+	}
+
+	memCells := make([]*memoryCell, len(values))
+	tVars := make([]TVar, len(values))
+	for i, value := range values {
+		memCells[i] = newMemCell(stm, value)
+		tVars[i] = TVar(memCells[i])
+	}
+	stm.create(memCells...)
+	return tVars
+}
+
+// create commits the creation of the new memory cells, it is a commit of its own. It
+// panics when the memory cells can't be logged on a persisted STM, nothing is created
+// then. The caller must hold the commit lock.
+func (stm *STM) create(memCells ...*memoryCell) {
+	version := stm.version + 1
+	values := make([]Value, len(memCells))
+	for i, memCell := range memCells {
+		memCell.lastVersion = version // the memory cell didn't exist before this version
+		values[i] = memCell.data
+	}
+	if err := stm.logCells(version, memCells, values); err != nil {
+		panic(fmt.Errorf("stm: creating memory cells: %w", err))
+	}
+	stm.version = version
+	stm.recordCreation(memCells...)
+	stm.addMemCells(memCells...)
+}
+
+// checkNames checks that the names of the new memory cells are neither taken nor given
+// twice. The caller must hold the commit lock.
+func (stm *STM) checkNames(memCells []*memoryCell) error {
+	given := make(map[string]bool)
+	for _, memCell := range memCells {
+		if memCell.name == "" {
+			continue
+		}
+		if _, ok := stm.lookup(memCell.name); ok || given[memCell.name] {
+			return fmt.Errorf("stm: creating memory cell %q: %w", memCell.name, ErrDuplicateName)
+		}
+		given[memCell.name] = true
+	}
+	return nil
+}
+
+// Version gives the current version of the STM, the number of commits so far.
+func (stm *STM) Version() uint64 {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	return stm.version
+}
+
+// addMemCells adds the memory cells to the memory of the STM, and the named ones to the
+// index of the names. The caller must hold the commit lock.
+func (stm *STM) addMemCells(memCells ...*memoryCell) {
+	if len(memCells) == 0 {
+		return
+	}
+	for _, memCell := range memCells {
+		if memCell.name != "" {
+			stm.names[memCell.name] = memCell
+		}
+	}
+	stm.memoryLock.Lock()
+	defer stm.memoryLock.Unlock()
+	stm.memory = append(stm.memory, memCells...)
+}
+
 // Perform accepts the transactional actions submitted to the STM and performs them.
 func (stm *STM) Perform(actions ...func(*Transaction) bool) {
 	for _, action := range actions {
@@ -72,14 +240,60 @@ func (stm *STM) Perform(actions ...func(*Transaction) bool) {
 	}
 }
 
+// PerformLabelled is Perform for transactions with a label. The label describes
+// the transactions in errors, metrics and traces.
+func (stm *STM) PerformLabelled(label string, actions ...func(*Transaction) bool) {
+	for _, action := range actions {
+		t := newTransaction(stm, action)
+		t.label = label
+		t.execute()
+	}
+}
+
+// Do performs the transactional action and waits for it to complete. It returns
+// the error the transaction was aborted with, nil when it has committed.
+func (stm *STM) Do(action func(*Transaction) bool) error {
+	return stm.DoContext(context.Background(), action)
+}
+
+// DoLabelled is Do for a transaction with a label, see PerformLabelled.
+func (stm *STM) DoLabelled(label string, action func(*Transaction) bool) error {
+	return stm.DoContext(ContextWithLabel(context.Background(), label), action)
+}
+
+// DoContext is Do with a context. The context is handed to the tracer of the STM,
+// the span of the transaction becomes a child of the span in the context. The
+// transaction is labelled with the label in the context, see ContextWithLabel.
+func (stm *STM) DoContext(ctx context.Context, action func(*Transaction) bool) error {
+	t := newTransaction(stm, action)
+	t.ctx = ctx
+	t.label, _ = ctx.Value(labelKey{}).(string)
+	t.run()
+	return t.err
+}
+
+// labelKey is the context key of the transaction label in a context.
+type labelKey struct{}
+
+// ContextWithLabel gives a context carrying the label for the transactions performed
+// with DoContext in it.
+func ContextWithLabel(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, labelKey{}, label)
+}
+
 // newTransaction makes a new transaction for the given action.
 func newTransaction(stm *STM, action func(*Transaction) bool) *Transaction {
 	t := new(Transaction)
+	t.id = atomic.AddUint64(&stm.transactions, 1)
 	t.version = 0
+	t.attempts = 0
+	t.ctx = context.Background()
 	t.isComplete = false
 	t.action = action
 	t.readQuarantine = make(map[*memoryCell]Value)
 	t.writeQuarantine = make(map[*memoryCell]Value)
+	t.commutes = make(map[*memoryCell][]func(Value) Value)
+	t.recomputed = make(map[*memoryCell]*computation)
 	t.stm = stm
 	return t
 }
@@ -94,11 +308,21 @@ func (stm *STM) releaseCommitLock() {
 	stm.commitLock.Unlock()
 }
 
-// PrintState prints the current state of all the memory cells -- just a snapshot
+// PrintState logs the current state of all the memory cells, their Snapshot as JSON.
+// It holds the commit lock while taking the snapshot, so the state printed is consistent.
 func (stm *STM) PrintState() {
-	log.Println("------- State of the STM -------- ")
-	for _, memCell := range stm.memory {
-		log.Println(memCell.toString())
+	stm.acquireCommitLock()
+	snap := stm.snapshot()
+	stm.releaseCommitLock()
+	printSnapshot(snap)
+}
+
+// printSnapshot logs the snapshot as JSON.
+func printSnapshot(snap *Snapshot) {
+	var buf bytes.Buffer
+	if err := snap.WriteJSON(&buf); err != nil {
+		log.Printf("stm: printing the state: %v", err)
+		return
 	}
-	log.Println("------- END -------- ")
+	log.Print(buf.String())
 }
@@ -0,0 +1,172 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// stm_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:19:41 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:19:41 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// labels is a metrics sink receiving the labels of the completed transactions.
+type labels chan string
+
+func (l labels) TransactionStarted(id uint64)                            {}
+func (l labels) AttemptAborted(id uint64, reason AbortReason, err error) {}
+func (l labels) CommitLockWaited(wait time.Duration)                     {}
+
+func (l labels) TransactionCompleted(report *TransactionReport) {
+	l <- report.Label
+}
+
+// expectDuplicateName checks fn panics with ErrDuplicateName.
+func expectDuplicateName(t *testing.T, fn func()) {
+	t.Helper()
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrDuplicateName) {
+			t.Errorf("got %v, want ErrDuplicateName", err)
+		}
+	}()
+	fn()
+}
+
+func TestNewNamedTVarNamesAreUnique(t *testing.T) {
+	s := New()
+	balance := s.NewNamedTVar("balance", counterValue(1))
+	s.NewTVar(counterValue(0))
+	s.NewTVar(counterValue(0)) // the unnamed memory cells never collide
+
+	version := s.Version()
+	expectDuplicateName(t, func() { s.NewNamedTVar("balance", counterValue(2)) })
+	if s.Version() != version {
+		t.Error("the rejected memory cell advanced the version")
+	}
+	if tVar, ok := s.Lookup("balance"); !ok || tVar != balance || countOf(tVar) != 1 {
+		t.Error("Lookup(balance) doesn't give the first memory cell with the name")
+	}
+	if _, ok := s.Lookup(""); ok {
+		t.Error("Lookup found an unnamed memory cell")
+	}
+	if _, ok := s.Lookup("missing"); ok {
+		t.Error("Lookup found a memory cell that doesn't exist")
+	}
+
+	err := s.Do(func(t *Transaction) bool {
+		t.NewNamedTVar("balance", counterValue(3))
+		return true
+	})
+	if !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("creating a taken name in a transaction: got %v, want ErrDuplicateName", err)
+	}
+	err = s.Do(func(t *Transaction) bool {
+		t.NewNamedTVar("savings", counterValue(3))
+		t.NewNamedTVar("savings", counterValue(4))
+		return true
+	})
+	if !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("creating a name twice in a transaction: got %v, want ErrDuplicateName", err)
+	}
+	if _, ok := s.Lookup("savings"); ok {
+		t.Error("the aborted transaction created its memory cells")
+	}
+
+	var savings TVar
+	s.Do(func(t *Transaction) bool {
+		savings = t.NewNamedTVar("savings", counterValue(5))
+		return true
+	})
+	if tVar, ok := s.Lookup("savings"); !ok || tVar != savings {
+		t.Error("Lookup doesn't find the memory cell created by a transaction")
+	}
+}
+
+func TestRecoveredCellIsReclaimedOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stm.wal")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.NewNamedTVar("balance", counterValue(7))
+	s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if tVar, ok := s.Lookup("balance"); !ok || countOf(tVar) != 7 {
+		t.Error("Lookup doesn't find the recovered memory cell")
+	}
+	balance := s.NewNamedTVar("balance", counterValue(0))
+	if countOf(balance) != 7 {
+		t.Errorf("reclaimed balance %v, want the recovered 7", countOf(balance))
+	}
+	expectDuplicateName(t, func() { s.NewNamedTVar("balance", counterValue(0)) })
+}
+
+func TestTransactionLabels(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(0))
+	completed := make(labels, 1)
+	s.SetMetricsSink(completed)
+	write := func(t *Transaction) bool { return t.Write(tVar, counterValue(1)) }
+
+	s.PerformLabelled("performed", write)
+	if label := <-completed; label != "performed" {
+		t.Errorf("PerformLabelled: the report's label is %q", label)
+	}
+	s.DoLabelled("done", write)
+	if label := <-completed; label != "done" {
+		t.Errorf("DoLabelled: the report's label is %q", label)
+	}
+	s.DoContext(ContextWithLabel(context.Background(), "in context"), func(tx *Transaction) bool {
+		if tx.Label() != "in context" {
+			t.Errorf("Label: got %q, want the label of the context", tx.Label())
+		}
+		return write(tx)
+	})
+	if label := <-completed; label != "in context" {
+		t.Errorf("ContextWithLabel: the report's label is %q", label)
+	}
+	s.Do(write)
+	if label := <-completed; label != "" {
+		t.Errorf("Do: the report's label is %q, want none", label)
+	}
+}
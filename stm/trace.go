@@ -0,0 +1,130 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// trace.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:47:47 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:48:32 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"strings"
+)
+
+// Tracer traces the transactions performed by an STM. Every transaction is traced by a
+// span of its own, with an event for every attempt that is rolled back and for the commit.
+type Tracer interface {
+	// Start starts the span of a transaction, as a child of the span in the context if any.
+	Start(ctx context.Context, name string) Span
+}
+
+// Span traces a single transaction.
+type Span interface {
+	// AddEvent records an event of the transaction.
+	AddEvent(name string, attrs ...Attribute)
+	// End ends the span. The error is the one the transaction was aborted with, nil
+	// when it has committed.
+	End(err error)
+}
+
+// Attribute is a key-value pair describing an event. The value is an int, a uint64
+// or a string.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// tracerHolder holds the tracer of an STM in an atomic.Value.
+type tracerHolder struct {
+	tracer Tracer
+}
+
+// SetTracer sets the tracer tracing the transactions, nil removes it.
+func (stm *STM) SetTracer(tracer Tracer) {
+	stm.tracer.Store(tracerHolder{tracer: tracer})
+}
+
+// startSpan starts the span of the transaction when the STM has a tracer.
+func (stm *STM) startSpan(t *Transaction) {
+	holder, _ := stm.tracer.Load().(tracerHolder)
+	if holder.tracer == nil {
+		return
+	}
+	name := t.label
+	if name == "" {
+		name = "stm.transaction"
+	}
+	t.span = holder.tracer.Start(t.ctx, name)
+	t.span.AddEvent("start", Attribute{"transaction", t.id}, Attribute{"label", t.label})
+}
+
+// traceAbort records the rollback of the current attempt of the transaction, and its
+// causes when it conflicted.
+func (t *Transaction) traceAbort(reason AbortReason, err error) {
+	if t.span == nil {
+		return
+	}
+	attrs := []Attribute{
+		{"attempt", t.attempts},
+		{"reason", reason.String()},
+		{"read_set", len(t.readQuarantine)},
+		{"write_set", len(t.writeQuarantine) + len(t.commutes) + len(t.newCells)},
+	}
+	if conflict, ok := err.(*ConflictError); ok {
+		cells := make([]string, len(conflict.Conflicts))
+		for i, c := range conflict.Conflicts {
+			cells[i] = c.Label()
+		}
+		attrs = append(attrs, Attribute{"conflicts", strings.Join(cells, ",")})
+	} else if err != nil {
+		attrs = append(attrs, Attribute{"error", err.Error()})
+	}
+	t.span.AddEvent("rollback", attrs...)
+}
+
+// endSpan records the commit of the transaction, if it has committed, and ends its span.
+func (t *Transaction) endSpan() {
+	if t.span == nil {
+		return
+	}
+	if t.err == nil {
+		t.span.AddEvent("commit",
+			Attribute{"attempt", t.attempts},
+			Attribute{"version", t.version},
+			Attribute{"read_set", t.readSetSize},
+			Attribute{"write_set", t.writeSetSize})
+	}
+	t.span.End(t.err)
+	t.span = nil
+}
@@ -0,0 +1,122 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// oteltracing.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:47:47 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:17:42 GMT-0700 (PDT)
+//
+
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// Tracer adapts an OpenTelemetry tracer to the stm.Tracer interface, e.g.
+//
+//	STM.SetTracer(oteltracing.NewTracer(otel.Tracer("github.com/sidmishraw/gostm")))
+//
+// The transactions performed with STM.DoContext are traced as children of the span
+// in the context.
+type Tracer struct {
+	tracer trace.Tracer // the OpenTelemetry tracer starting the spans
+}
+
+// span adapts an OpenTelemetry span to the stm.Span interface.
+type span struct {
+	span trace.Span // the OpenTelemetry span
+}
+
+// NewTracer creates a new tracer starting its spans with the OpenTelemetry `tracer`.
+func NewTracer(tracer trace.Tracer) *Tracer {
+	t := new(Tracer)
+	t.tracer = tracer
+	return t
+}
+
+// Start makes Tracer conform to the stm.Tracer interface.
+func (t *Tracer) Start(ctx context.Context, name string) stm.Span {
+	_, otelSpan := t.tracer.Start(ctx, name)
+	s := new(span)
+	s.span = otelSpan
+	return s
+}
+
+// AddEvent makes span conform to the stm.Span interface.
+func (s *span) AddEvent(name string, attrs ...stm.Attribute) {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		kvs[i] = keyValue(attr)
+	}
+	s.span.AddEvent(name, trace.WithAttributes(kvs...))
+}
+
+// End makes span conform to the stm.Span interface. An aborted transaction's span
+// records the error and has the error status.
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	} else {
+		s.span.SetStatus(codes.Ok, "")
+	}
+	s.span.End()
+}
+
+// keyValue converts the attribute to an OpenTelemetry attribute.
+func keyValue(attr stm.Attribute) attribute.KeyValue {
+	switch v := attr.Value.(type) {
+	case int:
+		return attribute.Int(attr.Key, v)
+	case int64:
+		return attribute.Int64(attr.Key, v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return attribute.String(attr.Key, strconv.FormatUint(v, 10)) // would wrap around as an int64
+		}
+		return attribute.Int64(attr.Key, int64(v))
+	case bool:
+		return attribute.Bool(attr.Key, v)
+	case string:
+		return attribute.String(attr.Key, v)
+	}
+	return attribute.String(attr.Key, fmt.Sprint(attr.Value))
+}
@@ -0,0 +1,177 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// oteltracing_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:17:42 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:17:42 GMT-0700 (PDT)
+//
+
+package oteltracing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// amount is the value of the memory cells of the tests.
+type amount int
+
+// MakeCopy makes amount conform to the stm.Value interface.
+func (a amount) MakeCopy() stm.Value {
+	return a
+}
+
+// IsEqual makes amount conform to the stm.Value interface.
+func (a amount) IsEqual(v stm.Value) bool {
+	other, ok := v.(amount)
+	return ok && other == a
+}
+
+// memoryTracer is an OpenTelemetry tracer keeping its spans in memory. The embedded
+// interfaces are nil, only the methods the adapter calls are implemented.
+type memoryTracer struct {
+	trace.Tracer
+	spans []*memorySpan
+}
+
+// memorySpan is a span of a memoryTracer.
+type memorySpan struct {
+	trace.Span
+	name        string
+	events      map[string][]attribute.KeyValue
+	errs        []error
+	status      codes.Code
+	description string
+	ended       bool
+}
+
+func (tr *memoryTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &memorySpan{name: name, events: make(map[string][]attribute.KeyValue)}
+	tr.spans = append(tr.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+func (span *memorySpan) AddEvent(name string, opts ...trace.EventOption) {
+	config := trace.NewEventConfig(opts...)
+	span.events[name] = config.Attributes()
+}
+
+func (span *memorySpan) RecordError(err error, opts ...trace.EventOption) {
+	span.errs = append(span.errs, err)
+}
+
+func (span *memorySpan) SetStatus(code codes.Code, description string) {
+	span.status, span.description = code, description
+}
+
+func (span *memorySpan) End(opts ...trace.SpanEndOption) {
+	span.ended = true
+}
+
+// valueOf gives the value of the attribute `key` in the attributes.
+func valueOf(attrs []attribute.KeyValue, key string) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracerTracesTheTransactions(t *testing.T) {
+	s := stm.New()
+	tracer := new(memoryTracer)
+	s.SetTracer(NewTracer(tracer))
+	errNegative := errors.New("negative")
+	balance := s.NewTVar(amount(0), func(v stm.Value) error {
+		if v.(amount) < 0 {
+			return errNegative
+		}
+		return nil
+	})
+
+	s.DoLabelled("deposit", func(t *stm.Transaction) bool { return t.Write(balance, amount(1)) })
+	s.Do(func(t *stm.Transaction) bool { return t.Write(balance, amount(-1)) })
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("%d spans, want 2", len(tracer.spans))
+	}
+	deposit, failed := tracer.spans[0], tracer.spans[1]
+	if deposit.name != "deposit" || !deposit.ended || deposit.status != codes.Ok || len(deposit.errs) != 0 {
+		t.Errorf("the deposit's span is %q, ended %v, status %v, errors %v", deposit.name, deposit.ended, deposit.status, deposit.errs)
+	}
+	if v, ok := valueOf(deposit.events["commit"], "version"); !ok || v.AsInt64() != int64(s.Version()) {
+		t.Errorf("the commit event's version is %v, want %d", v.Emit(), s.Version())
+	}
+	if v, ok := valueOf(deposit.events["start"], "label"); !ok || v.AsString() != "deposit" {
+		t.Errorf("the start event's label is %v, want deposit", v.Emit())
+	}
+
+	if failed.name != "stm.transaction" || !failed.ended || failed.status != codes.Error {
+		t.Errorf("the failed transaction's span is %q, ended %v, status %v", failed.name, failed.ended, failed.status)
+	}
+	if len(failed.errs) != 1 || !errors.Is(failed.errs[0], errNegative) {
+		t.Errorf("the failed transaction's span recorded %v, want the validation error", failed.errs)
+	}
+	if v, ok := valueOf(failed.events["rollback"], "reason"); !ok || v.AsString() != "invalid" {
+		t.Errorf("the rollback event's reason is %v, want invalid", v.Emit())
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	for _, test := range []struct {
+		value interface{}
+		want  attribute.Value
+	}{
+		{7, attribute.IntValue(7)},
+		{int64(-7), attribute.Int64Value(-7)},
+		{uint64(7), attribute.Int64Value(7)},
+		{uint64(math.MaxInt64), attribute.Int64Value(math.MaxInt64)},
+		{uint64(math.MaxUint64), attribute.StringValue("18446744073709551615")},
+		{true, attribute.BoolValue(true)},
+		{"deposit", attribute.StringValue("deposit")},
+		{1.5, attribute.StringValue("1.5")},
+	} {
+		kv := keyValue(stm.Attribute{Key: "k", Value: test.value})
+		if kv.Key != "k" || kv.Value != test.want {
+			t.Errorf("keyValue(%v): got %v, want %v", test.value, kv.Value.Emit(), test.want.Emit())
+		}
+	}
+}
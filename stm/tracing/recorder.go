@@ -0,0 +1,142 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// recorder.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:47:47 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:47:47 GMT-0700 (PDT)
+//
+
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// Recorder is a tracer that keeps the spans of the transactions in memory, so that
+// they can be inspected, e.g. in tests or when debugging a transaction that retries
+// too often.
+type Recorder struct {
+	lock  *sync.Mutex     // guards the spans
+	spans []*RecordedSpan // the spans started so far, in order
+}
+
+// RecordedSpan is a span kept by a Recorder.
+type RecordedSpan struct {
+	Name    string          // the name of the span
+	Parent  *RecordedSpan   // the span in the context the span was started in, if recorded by the same recorder
+	Started time.Time       // the time the span started
+	Ended   time.Time       // the time the span ended, zero while it is running
+	Events  []RecordedEvent // the events of the span, in order
+	Err     error           // the error the span ended with
+
+	lock *sync.Mutex // guards the span, shared with the recorder
+}
+
+// RecordedEvent is an event of a RecordedSpan.
+type RecordedEvent struct {
+	Name  string                 // the name of the event
+	Time  time.Time              // the time of the event
+	Attrs map[string]interface{} // the attributes of the event
+}
+
+// spanKey is the context key of the RecordedSpan in a context.
+type spanKey struct{}
+
+// NewRecorder creates a new empty recorder.
+func NewRecorder() *Recorder {
+	r := new(Recorder)
+	r.lock = new(sync.Mutex)
+	return r
+}
+
+// ContextWithSpan gives a context carrying the span, transactions performed in the
+// context are recorded as its children.
+func ContextWithSpan(ctx context.Context, span *RecordedSpan) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// Start makes Recorder conform to the stm.Tracer interface.
+func (r *Recorder) Start(ctx context.Context, name string) stm.Span {
+	span := new(RecordedSpan)
+	span.Name = name
+	span.Parent, _ = ctx.Value(spanKey{}).(*RecordedSpan)
+	span.Started = time.Now()
+	span.lock = r.lock
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = append(r.spans, span)
+	return span
+}
+
+// Spans gives copies of the spans recorded so far, in the order they were started.
+func (r *Recorder) Spans() []RecordedSpan {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	spans := make([]RecordedSpan, len(r.spans))
+	for i, span := range r.spans {
+		spans[i] = *span
+		spans[i].Events = append([]RecordedEvent(nil), span.Events...)
+	}
+	return spans
+}
+
+// Reset forgets the spans recorded so far.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.spans = nil
+}
+
+// AddEvent makes RecordedSpan conform to the stm.Span interface.
+func (span *RecordedSpan) AddEvent(name string, attrs ...stm.Attribute) {
+	event := RecordedEvent{Name: name, Time: time.Now(), Attrs: make(map[string]interface{}, len(attrs))}
+	for _, attr := range attrs {
+		event.Attrs[attr.Key] = attr.Value
+	}
+
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.Events = append(span.Events, event)
+}
+
+// End makes RecordedSpan conform to the stm.Span interface.
+func (span *RecordedSpan) End(err error) {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.Ended = time.Now()
+	span.Err = err
+}
@@ -0,0 +1,142 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// recorder_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:17:42 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:17:42 GMT-0700 (PDT)
+//
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sidmishraw/gostm/stm"
+)
+
+// amount is the value of the memory cells of the tests.
+type amount int
+
+// MakeCopy makes amount conform to the stm.Value interface.
+func (a amount) MakeCopy() stm.Value {
+	return a
+}
+
+// IsEqual makes amount conform to the stm.Value interface.
+func (a amount) IsEqual(v stm.Value) bool {
+	other, ok := v.(amount)
+	return ok && other == a
+}
+
+// eventNames gives the names of the events of the span.
+func eventNames(span RecordedSpan) (names []string) {
+	for _, event := range span.Events {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+func TestRecorderRecordsTheTransactions(t *testing.T) {
+	s := stm.New()
+	r := NewRecorder()
+	s.SetTracer(r)
+	balance := s.NewNamedTVar("balance", amount(0))
+
+	parent := r.Start(context.Background(), "request").(*RecordedSpan)
+	attempts := 0
+	err := s.DoContext(stm.ContextWithLabel(ContextWithSpan(context.Background(), parent), "deposit"), func(tx *stm.Transaction) bool {
+		attempts++
+		v := tx.Read(balance).(amount)
+		if attempts == 1 {
+			// a concurrent transaction conflicts with the first attempt
+			s.Do(func(t *stm.Transaction) bool { return t.Write(balance, amount(5)) })
+		}
+		return tx.Write(balance, v+1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := r.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("%d spans recorded, want the request, the deposit and the concurrent transaction", len(spans))
+	}
+	deposit, concurrent := spans[1], spans[2]
+	if deposit.Name != "deposit" || deposit.Parent != parent || deposit.Ended.IsZero() || deposit.Err != nil {
+		t.Errorf("the deposit's span is %q, parent %v, ended at %v with %v", deposit.Name, deposit.Parent, deposit.Ended, deposit.Err)
+	}
+	if concurrent.Name != "stm.transaction" || concurrent.Parent != nil {
+		t.Errorf("the concurrent transaction's span is %q, parent %v", concurrent.Name, concurrent.Parent)
+	}
+	names := eventNames(deposit)
+	if len(names) != 3 || names[0] != "start" || names[1] != "rollback" || names[2] != "commit" {
+		t.Fatalf("the deposit's events are %v, want start, rollback and commit", names)
+	}
+	rollback := deposit.Events[1].Attrs
+	if rollback["reason"] != "conflict" || rollback["conflicts"] != "balance" || rollback["attempt"] != 1 {
+		t.Errorf("the rollback's attributes are %v, want a conflict on balance in attempt 1", rollback)
+	}
+	if commit := deposit.Events[2].Attrs; commit["attempt"] != 2 || commit["version"] != s.Version() {
+		t.Errorf("the commit's attributes are %v, want attempt 2 at version %d", commit, s.Version())
+	}
+
+	r.Reset()
+	if len(r.Spans()) != 0 {
+		t.Error("Reset kept the spans")
+	}
+}
+
+func TestRecorderRecordsTheAbort(t *testing.T) {
+	s := stm.New()
+	r := NewRecorder()
+	s.SetTracer(r)
+	errNegative := errors.New("negative")
+	balance := s.NewTVar(amount(0), func(v stm.Value) error {
+		if v.(amount) < 0 {
+			return errNegative
+		}
+		return nil
+	})
+
+	s.Do(func(t *stm.Transaction) bool { return t.Write(balance, amount(-1)) })
+
+	spans := r.Spans()
+	if len(spans) != 1 || !errors.Is(spans[0].Err, errNegative) {
+		t.Fatalf("got %d spans, want one ended with the validation error", len(spans))
+	}
+	names := eventNames(spans[0])
+	if len(names) != 2 || names[1] != "rollback" || spans[0].Events[1].Attrs["reason"] != "invalid" {
+		t.Errorf("the events are %v, want start and an invalid rollback", names)
+	}
+}
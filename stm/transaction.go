@@ -32,95 +32,441 @@
 // transaction.go
 // @author Sidharth Mishra
 // @created Fri Mar 30 2018 19:37:11 GMT-0700 (PDT)
-// @last-modified Thu Apr 12 2018 23:37:14 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
 //
 
 package stm
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
 // Transaction is the only way to modify the memory cells in the STM.
 type Transaction struct {
-	version         int                     // version of the transaction
-	isComplete      bool                    // flag showing if the transaction is running or is complete
-	action          func(*Transaction) bool // the action that this transaction executes
-	readQuarantine  map[*memoryCell]Value   // the read quarantine
-	writeQuarantine map[*memoryCell]Value   // the write quarantine
-	stm             *STM                    // the reference to the STM this transaction intends to modify
+	id              uint64                              // the unique identity of the transaction within the STM
+	label           string                              // the label describing the transaction, empty if none
+	version         uint64                              // version of the STM the transaction committed at
+	attempts        int                                 // the number of times the action has been attempted
+	readSetSize     int                                 // the size of the read quarantine at commit
+	writeSetSize    int                                 // the number of memory cells written or created at commit
+	started         time.Time                           // the time the transaction started
+	ctx             context.Context                     // the context the transaction is performed in
+	span            Span                                // the span tracing the transaction, nil when not traced
+	isComplete      bool                                // flag showing if the transaction is running or is complete
+	readOnly        bool                                // the transaction only reads the STM as of its version, see STM.ReadAt
+	action          func(*Transaction) bool             // the action that this transaction executes
+	readQuarantine  map[*memoryCell]Value               // the read quarantine
+	writeQuarantine map[*memoryCell]Value               // the write quarantine
+	commutes        map[*memoryCell][]func(Value) Value // the commutative updates, applied at commit
+	newCells        []*memoryCell                       // the memory cells created by this transaction
+	changes         []cellChange                        // the changes made by the commit to watched memory cells
+	record          *ChangeRecord                       // the record of the commit for the changefeed, nil when it is disabled
+	tracking        []map[*memoryCell]bool              // the reads tracked for the computed TVars being computed
+	recomputed      map[*memoryCell]*computation        // the computed TVars computed by this transaction
+	err             error                               // the reason the transaction was aborted, nil if it committed
+	stm             *STM                                // the reference to the STM this transaction intends to modify
+}
+
+// ID gives the unique identity of the transaction within its STM.
+func (t *Transaction) ID() uint64 {
+	return t.id
+}
+
+// Label gives the label the transaction was performed with, empty if none.
+func (t *Transaction) Label() string {
+	return t.label
+}
+
+// Attempt gives the number of the current attempt of the transaction, starting at 1.
+func (t *Transaction) Attempt() int {
+	return t.attempts
+}
+
+// Version gives the version of the STM the transaction committed at, 0 until it has committed.
+func (t *Transaction) Version() uint64 {
+	return t.version
+}
+
+// memCellOf gives the memory cell referenced by the `tVar`. It panics when the memory
+// cell belongs to another STM, its contents would be read or written without the
+// commit lock of its STM; the transactions over several STMs are made by DoAcross.
+func (t *Transaction) memCellOf(tVar TVar) *memoryCell {
+	memCell := tVar.(*memoryCell)
+	if memCell.stm != t.stm {
+		panic(fmt.Errorf("stm: memory cell %s belongs to another STM, see DoAcross", memCell.label()))
+	}
+	return memCell
 }
 
 // Reads the contents of the memory cell referenced by the `tVar`.
+// If the transaction has already written to the memory cell, the written
+// value is returned instead -- the transaction sees its own writes.
 func (t *Transaction) Read(tVar TVar) Value {
-	memCell := tVar.(*memoryCell)
+	memCell := t.memCellOf(tVar)
+	if t.readOnly {
+		return t.readAt(memCell)
+	}
+	t.track(memCell)
+	if memCell.compute != nil {
+		return t.readComputed(memCell)
+	}
+	if val, ok := t.writeQuarantine[memCell]; ok {
+		return val.MakeCopy()
+	}
 	val := t.readQuarantine[memCell]
 	if val == nil {
 		val = memCell.read()
 		t.readQuarantine[memCell] = val
 	}
-	return val.MakeCopy()
+	val = val.MakeCopy()
+	for _, fn := range t.commutes[memCell] {
+		val = fn(val) // the transaction sees its own commutative updates too
+	}
+	return val
 }
 
 // Writes the new Data into the write quarantine. This will be flushed into the STM upon
 // successful commit.
 func (t *Transaction) Write(tVar TVar, newData Value) bool {
-	memCell := tVar.(*memoryCell)
+	memCell := t.memCellOf(tVar)
+	if memCell.compute != nil || t.readOnly {
+		return false // computed TVars can't be written to, read-only transactions can't write
+	}
 	t.writeQuarantine[memCell] = newData
+	delete(t.commutes, memCell) // the write overrides the pending commutative updates
+	return true
+}
+
+// Commute records a commutative update `fn` of the memory cell referenced by the `tVar`.
+// Unlike a Read followed by a Write, it doesn't put the memory cell in the read quarantine.
+// The update is applied to the latest contents of the memory cell at commit, so concurrent
+// transactions commuting the same memory cell don't conflict with each other.
+// The `fn` is given a copy of the contents and it must not depend on anything else the
+// transaction has read, the order it gets applied in with respect to other transactions is
+// not defined.
+func (t *Transaction) Commute(tVar TVar, fn func(Value) Value) bool {
+	memCell := t.memCellOf(tVar)
+	if memCell.compute != nil || t.readOnly {
+		return false // computed TVars can't be written to, read-only transactions can't write
+	}
+	if val, ok := t.writeQuarantine[memCell]; ok {
+		t.writeQuarantine[memCell] = fn(val.MakeCopy()) // already overwritten, apply right away
+		return true
+	}
+	t.commutes[memCell] = append(t.commutes[memCell], fn)
 	return true
 }
 
+// NewTVar creates a new memory cell from within the transaction and returns the
+// reference to it as a TVar instance. The memory cell is only added to the STM
+// when the transaction commits, so a rolled back transaction leaves nothing behind.
+// The `validators` are checked against every new value of the memory cell, see STM.NewTVar.
+func (t *Transaction) NewTVar(data Value, validators ...Validator) TVar {
+	return t.NewNamedTVar("", data, validators...)
+}
+
+// NewNamedTVar is NewTVar for a memory cell with a name, see STM.NewNamedTVar. The
+// transaction is aborted with an error wrapping ErrDuplicateName when the name is taken.
+func (t *Transaction) NewNamedTVar(name string, data Value, validators ...Validator) TVar {
+	memCell := newMemCell(t.stm, data)
+	memCell.name = name
+	memCell.validators = validators
+	t.newCells = append(t.newCells, memCell)
+	return TVar(memCell)
+}
+
+// retrySignal is raised by Retry to abandon the current attempt of the transaction.
+type retrySignal struct{}
+
+// ErrRetryWithoutReads is the reason a transaction calling Retry before reading any
+// memory cell is aborted, no change could ever wake it up.
+var ErrRetryWithoutReads = errors.New("stm: Retry in a transaction that has read nothing")
+
+// ErrActionPanicked is the error recorded for a transaction whose action panicked. The
+// panic goes on unwinding, the error only makes the transaction count as given up on.
+var ErrActionPanicked = errors.New("stm: the action panicked")
+
+// Retry abandons the current attempt of the transaction. The transaction is blocked
+// until another transaction changes one of the memory cells it has read, and then it
+// is retried. This is how a transaction waits for a condition, e.g. a non-empty queue.
+// A transaction that hasn't read anything is aborted with ErrRetryWithoutReads, and a
+// transaction performed with DoContext is aborted with the context's error when the
+// context is done while it is blocked.
+func (t *Transaction) Retry() {
+	panic(retrySignal{})
+}
+
 // Execute executes this transaction as another thread.
+// Nobody is waiting for the outcome, so an aborted transaction is only logged.
 func (t *Transaction) execute() {
-	go t.run()
+	go func() {
+		t.run()
+		if t.err != nil {
+			log.Printf("transaction %d %q aborted: %v", t.id, t.label, t.err)
+		}
+	}()
 }
 
 // The actual execution logic of the transaction.
 func (t *Transaction) run() {
+	t.stm.beginTransaction(t)
+	defer t.stm.endTransaction(t)
+
 	t.isComplete = false
 	for !t.isComplete {
-		if status := t.action(t); !status {
+		t.attempts++
+		status, retry := t.attempt()
+		if retry && len(t.readQuarantine) == 0 {
+			// nothing read, nothing to wait for, give up on the transaction
+			t.err = ErrRetryWithoutReads
+			t.stm.abortAttempt(t, AbortRetry, t.err)
+			t.rollback()
+			break
+		}
+		if retry {
+			// the transaction is waiting for its read set to change
+			t.isComplete = false
+			t.stm.abortAttempt(t, AbortRetry, nil)
+			err := t.awaitChange(t.ctx)
+			t.rollback()
+			if err != nil {
+				// the context was done before anything changed, give up on the transaction
+				t.err = err
+				break
+			}
+			continue
+		}
+		if !status {
 			// failed to execute the action
 			t.isComplete = false
+			t.stm.abortAttempt(t, AbortFailed, nil)
 			t.rollback()
 			continue
 		}
-		if status := t.commit(); !status {
-			// failed to commit
+		err := t.commit()
+		if conflict, ok := err.(*ConflictError); ok {
+			// failed to commit, the contention manager decides if it is retried
 			t.isComplete = false
+			t.stm.abortAttempt(t, AbortConflict, conflict)
 			t.rollback()
+			if err := t.stm.contend(conflict); err != nil {
+				t.err = err
+				break
+			}
 			continue
 		}
+		if err != nil {
+			// the commit was rejected, give up on the transaction
+			t.err = err
+			t.stm.abortAttempt(t, AbortInvalid, err)
+			t.rollback()
+			break
+		}
 		t.isComplete = true
 	}
-	t.version++
+	t.stm.notifyWatchers(t.changes) // delivered after the commit lock has been released
+	if t.record != nil {
+		t.stm.feed.throttle(t.ctx) // waits for the subscribers to catch up
+	}
+	t.changes = nil
+}
+
+// attempt runs the action of the transaction once. It reports if the action
+// asked for the transaction to be retried by calling Retry.
+func (t *Transaction) attempt() (status bool, retry bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(retrySignal); !ok {
+				t.abandon(r)
+				panic(r) // not ours to handle
+			}
+			status, retry = false, true
+		}
+	}()
+	return t.action(t), false
+}
+
+// abandon rolls back the attempt of an action that panicked with `r`. The transaction is
+// given up on, so the deferred endTransaction counts it as a failure and not a commit.
+func (t *Transaction) abandon(r interface{}) {
+	t.err = fmt.Errorf("%w: %v", ErrActionPanicked, r)
+	t.isComplete = false
+	t.stm.abortAttempt(t, AbortFailed, t.err)
+	t.rollback()
+}
+
+// awaitChange blocks until one of the memory cells in the read quarantine has
+// been changed by another transaction. It gives back the error of the context
+// when it is done first.
+func (t *Transaction) awaitChange(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		t.stm.acquireCommitLock()
+		defer t.stm.releaseCommitLock()
+		t.stm.commitCond.Broadcast() // wakes up the transaction to see its context is done
+	})
+	defer stop()
+
+	t.stm.acquireCommitLock()
+	defer t.stm.releaseCommitLock()
+
+	for !t.isReadSetChanged() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t.stm.commitCond.Wait() // woken up after every commit
+	}
+	return nil
+}
+
+// isReadSetChanged checks if any of the memory cells in the read quarantine
+// no longer holds the value that was read by the transaction.
+func (t *Transaction) isReadSetChanged() bool {
+	for memCell, value := range t.readQuarantine {
+		if !memCell.read().IsEqual(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// publish writes the value into the memory cell while committing. When the memory cell
+// is being watched and its contents change, the change is recorded for the watchers.
+func (t *Transaction) publish(memCell *memoryCell, value Value) {
+	if t.stm.isWatched(memCell) && !memCell.read().IsEqual(value) {
+		t.changes = append(t.changes, cellChange{memCell: memCell, value: value.MakeCopy(), version: t.stm.version})
+	}
+	if t.record != nil && !memCell.data.IsEqual(value) { // the contents only change under the commit lock
+		t.record.Changes = append(t.record.Changes, Change{Cell: memCell.id, Name: memCell.name, Old: memCell.data.MakeCopy(), New: value})
+	}
+	memCell.writeVersion(value, t.stm.version, t.stm.historyFloor())
+	memCell.lastWriter = t.id
+	t.stm.invalidateDependents(memCell)
 }
 
 // rollback the transaction to the initial state so that it can retry.
 func (t *Transaction) rollback() {
 	t.readQuarantine = make(map[*memoryCell]Value)
 	t.writeQuarantine = make(map[*memoryCell]Value)
+	t.commutes = make(map[*memoryCell][]func(Value) Value)
+	t.newCells = nil
+	t.tracking = nil
+	t.recomputed = make(map[*memoryCell]*computation)
 }
 
-// commit the write quarantine memory cells into the STM.
-func (t *Transaction) commit() bool {
+// commit the write quarantine memory cells into the STM. It returns a ConflictError
+// when the read quarantine is stale and the transaction needs to be retried, and any
+// other error when the new values are rejected and the transaction needs to be aborted.
+func (t *Transaction) commit() error {
+	waitStart := time.Now()
 	t.stm.acquireCommitLock()       // acquire the commit lock on the STM
 	defer t.stm.releaseCommitLock() // release the commit lock on the STM
+	t.stm.commitLockWaited(time.Since(waitStart))
+
+	newValues, err := t.prepare()
+	if err != nil {
+		return err
+	}
+
+	if t.stm.readOnly {
+		t.version = t.stm.version
+		return nil // nothing to publish, the version of a replica follows its primary
+	}
+
+	if err := t.stm.logCommit(newValues, t.newCells); err != nil {
+		return err // the transaction is aborted, it isn't durable
+	}
 
-	failCount := 0
+	t.apply(newValues)
+	return nil // commit succeeded
+}
+
+// prepare validates the read quarantine and gives the new values of the memory cells
+// the commit writes, checked against the validators and invariants. It returns the
+// errors of commit. The caller must hold the commit lock.
+func (t *Transaction) prepare() (map[*memoryCell]Value, error) {
+	var conflicts []Conflict
 	for memCell, value := range t.readQuarantine {
 		currVal := memCell.read()
 		if !currVal.IsEqual(value) {
 			// data has changed by other transaction
 			// commit has failed
-			failCount++
+			conflicts = append(conflicts, memCell.conflict())
 		}
 	}
 
-	if failCount > 0 {
-		return false // commit failed
+	if len(conflicts) > 0 {
+		return nil, t.conflictError(conflicts) // commit failed
 	}
 
-	// read quarantined values have been verified
+	// the new values of the memory cells, the commutative updates
+	// are applied on top of the latest values
+	newValues := make(map[*memoryCell]Value, len(t.writeQuarantine)+len(t.commutes))
 	for memCell, value := range t.writeQuarantine {
-		memCell.write(value) // update the values
+		newValues[memCell] = value
+	}
+	for memCell, fns := range t.commutes {
+		value := memCell.read()
+		for _, fn := range fns {
+			value = fn(value)
+		}
+		newValues[memCell] = value
+	}
+
+	if t.stm.readOnly {
+		if len(newValues) > 0 || len(t.newCells) > 0 {
+			return nil, ErrReadOnly
+		}
+		return newValues, nil
+	}
+
+	if err := t.stm.checkNames(t.newCells); err != nil {
+		return nil, err // the transaction is aborted
+	}
+	if err := t.stm.validate(newValues, t.newCells); err != nil {
+		return nil, err // the transaction is aborted
+	}
+	return newValues, nil
+}
+
+// apply publishes the new values and the memory cells created by the transaction, as a
+// new version of the STM. The caller must hold the commit lock, the commit has been
+// prepared and logged.
+func (t *Transaction) apply(newValues map[*memoryCell]Value) {
+	t.stm.version++
+	t.version = t.stm.version
+	t.readSetSize, t.writeSetSize = len(t.readQuarantine), len(newValues)+len(t.newCells)
+
+	// read quarantined values have been verified, the computed TVars computed
+	// from them can be cached before the writes invalidate them
+	for memCell, c := range t.recomputed {
+		t.stm.cacheComputed(memCell, c)
+	}
+
+	if t.stm.feed.enabled() {
+		t.record = &ChangeRecord{Version: t.stm.version, TransactionID: t.id, Label: t.label}
+	}
+
+	for _, memCell := range t.newCells {
+		memCell.lastWriter = t.id
+		memCell.lastVersion = t.stm.version
+		if t.record != nil {
+			t.record.Changes = append(t.record.Changes, Change{Cell: memCell.id, Name: memCell.name, Created: true, New: memCell.data})
+		}
+	}
+
+	for memCell, value := range newValues {
+		t.publish(memCell, value) // update the values
+	}
+	t.stm.addMemCells(t.newCells...) // the new memory cells are now part of the STM
+	t.newCells = nil
+
+	if t.record != nil {
+		t.stm.feed.append(*t.record)
 	}
 
-	return true // commit succeeded
+	t.stm.commitCond.Broadcast() // wake up the transactions waiting for changes
 }
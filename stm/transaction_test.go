@@ -0,0 +1,106 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// transaction_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:20:06 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 20:55:42 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetryWithoutReads(t *testing.T) {
+	s := New()
+	err := s.Do(func(t *Transaction) bool {
+		t.Retry()
+		return true
+	})
+	if !errors.Is(err, ErrRetryWithoutReads) {
+		t.Fatalf("Retry without reads: got %v, want ErrRetryWithoutReads", err)
+	}
+}
+
+func TestRetryWaitsForChange(t *testing.T) {
+	s := New()
+	q := NewTPriorityQueue[int](s)
+
+	waiting := make(chan struct{})
+	popped := make(chan Value)
+	go func() {
+		var value Value
+		s.Do(func(t *Transaction) bool {
+			if q.Len(t) == 0 && t.Attempt() == 1 {
+				close(waiting) // the queue has been read empty, the push wakes up the retry
+			}
+			_, value = q.PopMinWait(t)
+			return true
+		})
+		popped <- value
+	}()
+
+	<-waiting
+	s.Do(func(t *Transaction) bool {
+		return q.Push(t, 1, counterValue(7))
+	})
+	if value := <-popped; value != counterValue(7) {
+		t.Fatalf("PopMinWait: got %v, want 7", value)
+	}
+}
+
+func TestRetryGivesUpWithTheContext(t *testing.T) {
+	s := New()
+	q := NewTPriorityQueue[int](s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan struct{})
+	aborted := make(chan error)
+	go func() {
+		aborted <- s.DoContext(ctx, func(t *Transaction) bool {
+			if t.Attempt() == 1 {
+				defer close(waiting)
+			}
+			q.PopMinWait(t)
+			return true
+		})
+	}()
+
+	<-waiting
+	cancel()
+	if err := <-aborted; !errors.Is(err, context.Canceled) {
+		t.Fatalf("DoContext: got %v, want context.Canceled", err)
+	}
+}
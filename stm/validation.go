@@ -0,0 +1,154 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// validation.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:44:01 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:48:32 GMT-0700 (PDT)
+//
+
+package stm
+
+import "fmt"
+
+// Validator checks a new value of a memory cell before it is committed. A non-nil error
+// rejects the value and aborts the transaction. Validators must not modify the value.
+type Validator func(value Value) error
+
+// invariant is a rule over several memory cells, checked by every commit writing to any of them.
+type invariant struct {
+	name     string                     // the name of the invariant, used in the errors
+	memCells []*memoryCell              // the memory cells the invariant is over
+	check    func(values []Value) error // checks the values of the memory cells, in order
+}
+
+// ValidationError is the error a transaction is aborted with when a validator or an
+// invariant rejects the values it is committing.
+type ValidationError struct {
+	Invariant string // the name of the violated invariant, empty when a validator rejected the value
+	Cell      string // the ID of the memory cell whose value was rejected, empty for invariants
+	Name      string // the name of the memory cell whose value was rejected, empty if none
+	Value     Value  // the rejected value, nil for invariants
+	Err       error  // the error given by the validator or the invariant
+}
+
+// Error makes ValidationError conform to the error interface.
+func (e *ValidationError) Error() string {
+	if e.Invariant != "" {
+		return fmt.Sprintf("stm: invariant %q violated: %v", e.Invariant, e.Err)
+	}
+	name := e.Name
+	if name == "" {
+		name = e.Cell
+	}
+	return fmt.Sprintf("stm: value %v of memory cell %s rejected: %v", e.Value, name, e.Err)
+}
+
+// Unwrap gives the error given by the validator or the invariant.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// AddInvariant adds an invariant over the memory cells referenced by the `tVars`. Every
+// commit writing to any of them calls `check` with the values the memory cells will
+// hold after the commit, in the order of the `tVars`. A non-nil error aborts the
+// transaction. The `check` runs under the commit lock, so it must be quick and must
+// not modify the values.
+func (stm *STM) AddInvariant(name string, tVars []TVar, check func(values []Value) error) {
+	inv := new(invariant)
+	inv.name = name
+	inv.memCells = make([]*memoryCell, len(tVars))
+	for i, tVar := range tVars {
+		inv.memCells[i] = tVar.(*memoryCell)
+	}
+	inv.check = check
+
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+	stm.invariants = append(stm.invariants, inv)
+}
+
+// validate checks the new values of the memory cells, and the initial values of the new
+// memory cells, against their validators and the invariants they are part of. It must be
+// called while holding the commit lock.
+func (stm *STM) validate(newValues map[*memoryCell]Value, newCells []*memoryCell) error {
+	for memCell, value := range newValues {
+		if err := memCell.validate(value); err != nil {
+			return err
+		}
+	}
+	for _, memCell := range newCells {
+		if _, ok := newValues[memCell]; !ok {
+			if err := memCell.validate(memCell.read()); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, inv := range stm.invariants {
+		affected := false
+		values := make([]Value, len(inv.memCells))
+		for i, memCell := range inv.memCells {
+			value, ok := newValues[memCell]
+			if !ok {
+				value = memCell.read()
+			}
+			affected = affected || ok
+			values[i] = value
+		}
+		if !affected {
+			continue
+		}
+		if err := inv.check(values); err != nil {
+			verr := new(ValidationError)
+			verr.Invariant = inv.name
+			verr.Err = err
+			return verr
+		}
+	}
+
+	return nil
+}
+
+// validate checks the value against the validators of the memory cell.
+func (memCell *memoryCell) validate(value Value) error {
+	for _, validator := range memCell.validators {
+		if err := validator(value); err != nil {
+			verr := new(ValidationError)
+			verr.Cell = memCell.id
+			verr.Name = memCell.name
+			verr.Value = value
+			verr.Err = err
+			return verr
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// validation_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:04:50 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:04:50 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"errors"
+	"testing"
+)
+
+// errNegative is the error of the validators and invariants of the tests.
+var errNegative = errors.New("negative")
+
+// nonNegative is a validator rejecting negative counts.
+func nonNegative(v Value) error {
+	if v.(counterValue) < 0 {
+		return errNegative
+	}
+	return nil
+}
+
+// countOf reads the count in the memory cell referenced by the `tVar`.
+func countOf(tVar TVar) counterValue {
+	return tVar.(*memoryCell).read().(counterValue)
+}
+
+func TestValidatorRejectsTheCommit(t *testing.T) {
+	s := New()
+	balance := s.NewNamedTVar("balance", counterValue(1), nonNegative)
+	other := s.NewTVar(counterValue(0))
+
+	err := s.Do(func(t *Transaction) bool {
+		t.Write(other, counterValue(7))
+		return t.Write(balance, counterValue(-1))
+	})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Do: got %v, want a *ValidationError", err)
+	}
+	if verr.Name != "balance" || verr.Cell != balance.(*memoryCell).id || verr.Value != counterValue(-1) || verr.Invariant != "" {
+		t.Errorf("rejected %s (%s) holding %v, invariant %q, want balance holding -1", verr.Name, verr.Cell, verr.Value, verr.Invariant)
+	}
+	if !errors.Is(err, errNegative) {
+		t.Errorf("the validation error doesn't wrap the validator's error: %v", err)
+	}
+	if countOf(balance) != 1 || countOf(other) != 0 {
+		t.Error("the rejected transaction changed the memory cells")
+	}
+
+	if err := s.Do(func(t *Transaction) bool { return t.Write(balance, counterValue(0)) }); err != nil {
+		t.Errorf("a valid value was rejected: %v", err)
+	}
+}
+
+func TestValidatorRejectsANewCell(t *testing.T) {
+	s := New()
+	var created TVar
+	err := s.Do(func(t *Transaction) bool {
+		created = t.NewTVar(counterValue(-1), nonNegative)
+		return true
+	})
+	if !errors.Is(err, errNegative) {
+		t.Fatalf("Do: got %v, want the new memory cell's value rejected", err)
+	}
+	for _, memCell := range s.memory {
+		if memCell == created {
+			t.Error("the rejected memory cell was added to the STM")
+		}
+	}
+}
+
+func TestInvariantRejectsTheCommit(t *testing.T) {
+	s := New()
+	a, b := s.NewTVar(counterValue(5)), s.NewTVar(counterValue(5))
+	untouched := s.NewTVar(counterValue(0))
+	checks := 0
+	s.AddInvariant("total is 10", []TVar{a, b}, func(values []Value) error {
+		checks++
+		if values[0].(counterValue)+values[1].(counterValue) != 10 {
+			return errors.New("unbalanced")
+		}
+		return nil
+	})
+
+	err := s.Do(func(t *Transaction) bool { return t.Write(a, counterValue(6)) })
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Invariant != "total is 10" {
+		t.Fatalf("Do: got %v, want the invariant violated", err)
+	}
+	if countOf(a) != 5 {
+		t.Error("the rejected transaction changed the memory cell")
+	}
+
+	err = s.Do(func(t *Transaction) bool {
+		t.Write(a, counterValue(6))
+		return t.Write(b, counterValue(4))
+	})
+	if err != nil {
+		t.Errorf("a transaction keeping the invariant was rejected: %v", err)
+	}
+
+	checks = 0
+	s.Do(func(t *Transaction) bool { return t.Write(untouched, counterValue(1)) })
+	if checks != 0 {
+		t.Error("the invariant was checked by a commit writing none of its memory cells")
+	}
+}
@@ -0,0 +1,609 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// wal.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:54:32 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:34:34 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrClosed is the reason the commits of a persisted STM are aborted after it is closed.
+var ErrClosed = errors.New("stm: the write-ahead log is closed")
+
+// SyncPolicy decides when the write-ahead log is flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes the log on every commit, before the commit returns. A commit
+	// that returned survives a crash. It is the default.
+	SyncAlways SyncPolicy = iota
+
+	// SyncPeriodically flushes the log in the background, see WithSyncInterval. A crash
+	// loses the commits since the last flush.
+	SyncPeriodically
+
+	// SyncNever leaves flushing the log to the operating system.
+	SyncNever
+)
+
+// defaultSyncInterval is the interval between the flushes of SyncPeriodically.
+const defaultSyncInterval = time.Second
+
+// Option configures a persisted STM, see Open.
+type Option func(*persistOptions)
+
+// persistOptions are the options of a persisted STM.
+type persistOptions struct {
+	fs                 FileSystem    // the file system of the log and the checkpoints
+	codec              Codec         // encodes and decodes the logged values
+	sync               SyncPolicy    // when the log is flushed
+	syncInterval       time.Duration // the interval between the flushes for SyncPeriodically
+	checkpointInterval time.Duration // the interval between the background checkpoints, none when 0
+}
+
+// WithCodec sets the codec the values are logged with, DefaultCodecs by default.
+func WithCodec(codec Codec) Option {
+	return func(opts *persistOptions) {
+		opts.codec = codec
+	}
+}
+
+// WithFileSystem sets the file system the log and the checkpoints are kept on.
+func WithFileSystem(fs FileSystem) Option {
+	return func(opts *persistOptions) {
+		opts.fs = fs
+	}
+}
+
+// WithSyncPolicy sets when the log is flushed to stable storage.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(opts *persistOptions) {
+		opts.sync = policy
+	}
+}
+
+// WithSyncInterval sets the log to be flushed every `interval` in the background,
+// it implies SyncPeriodically.
+func WithSyncInterval(interval time.Duration) Option {
+	return func(opts *persistOptions) {
+		opts.sync = SyncPeriodically
+		opts.syncInterval = interval
+	}
+}
+
+// wal is the write-ahead log of a persisted STM. Every commit appends a record with the
+// version of the STM and the memory cells it wrote. The records are framed by their
+// length and CRC, a torn record at the end of the log is a commit that never completed.
+type wal struct {
+	path  string         // the path of the log file
+	file  File           // the log file
+	lock  *sync.Mutex    // guards the log file against the background flushes and checkpoints
+	opts  persistOptions // the options of the log
+	size  int64          // the end of the last record appended
+	err   error          // the sticky error of the log, after a failed write the log is unusable
+	done  chan struct{}  // closed to stop the background flushes
+	dirty bool           // records were appended since the last flush
+
+	skipped map[*memoryCell]bool // the memory cells reported as holding values without a codec, guarded by the commit lock
+}
+
+// walEntry is a memory cell in a record of the log.
+type walEntry struct {
+	id   string // the ID of the memory cell
+	name string // the name of the memory cell
+	typ  string // the type of the encoded value, given by the codec
+	data []byte // the encoded value
+}
+
+// Open opens the persisted STM backed by the write-ahead log at `path`, creating the log
+// if it doesn't exist. The last checkpoint and the log are replayed to rebuild the memory
+// cells with the contents they had after the last logged commit. Every commit is then
+// appended to the log. The values are logged with the codec given by WithCodec, the
+// types of the values to persist must be registered with DefaultCodecs otherwise.
+//
+// Only the named memory cells holding values the codec encodes persist:
+//   - the recovered memory cells are reclaimed by name, NewNamedTVar gives back the
+//     recovered memory cell with the name instead of creating one, once;
+//   - the unnamed memory cells are logged, but nothing could reclaim them, they are
+//     dropped by the recovery and left out of the next checkpoint, Open logs how many;
+//   - the values the codec doesn't encode are left out of the log, a memory cell
+//     holding one is logged as skipped the first time, it is recovered with the last
+//     value encoded, or not at all;
+//   - the computed TVars are never logged, they are computed again.
+func Open(path string, opts ...Option) (*STM, error) {
+	o := persistOptions{fs: OSFileSystem, codec: DefaultCodecs, sync: SyncAlways, syncInterval: defaultSyncInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	stm := New()
+	memCells := make(map[string]*memoryCell)
+
+	o.fs.Remove(path + checkpointTempSuffix) // left behind by an interrupted checkpoint
+	checkpoint, err := o.fs.OpenFile(path+checkpointSuffix, os.O_RDONLY, 0)
+	if err == nil {
+		_, err = stm.replay(checkpoint, o.codec, memCells, 0)
+		checkpoint.Close()
+		if err != nil {
+			return nil, fmt.Errorf("stm: reading the checkpoint: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	file, err := o.fs.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := stm.replay(file, o.codec, memCells, stm.version)
+	if errors.Is(err, errTornRecord) {
+		log.Printf("stm: cutting off the end of the write-ahead log at %d: %v", offset, err)
+		err = file.Truncate(offset)
+	}
+	if err == nil {
+		_, err = file.Seek(offset, io.SeekStart) // the log is appended to after the last record
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// the unnamed memory cells are dropped, nothing could reclaim them
+	named := stm.memory[:0]
+	for _, memCell := range stm.memory {
+		if memCell.name != "" {
+			stm.names[memCell.name] = memCell
+			stm.unclaimed[memCell.name] = memCell
+			named = append(named, memCell)
+		}
+	}
+	if dropped := len(stm.memory) - len(named); dropped > 0 {
+		log.Printf("stm: dropped %d unnamed memory cells recovered from %s, only the named ones can be reclaimed", dropped, path)
+	}
+	stm.memory = named
+
+	w := new(wal)
+	w.path = path
+	w.file = file
+	w.size = offset
+	w.lock = new(sync.Mutex)
+	w.opts = o
+	w.done = make(chan struct{})
+	w.skipped = make(map[*memoryCell]bool)
+	if o.sync == SyncPeriodically {
+		go w.syncPeriodically()
+	}
+	if o.checkpointInterval > 0 {
+		go stm.checkpointPeriodically(o.checkpointInterval, w.done)
+	}
+	stm.wal = w
+
+	return stm, nil
+}
+
+// Close flushes and closes the write-ahead log of a persisted STM. The commits after it
+// are aborted with ErrClosed. Closing an STM that isn't persisted does nothing.
+func (stm *STM) Close() error {
+	stm.acquireCommitLock()
+	defer stm.releaseCommitLock()
+
+	if stm.wal == nil {
+		return nil
+	}
+	return stm.wal.close()
+}
+
+// errTornRecord is the reason the replay stops at a torn or corrupt record, the record
+// of a commit that never completed.
+var errTornRecord = errors.New("stm: torn record")
+
+// replay reads the records of the `file` and applies the ones at the version `from` or
+// later to the memory cells, creating the memory cells as they are met. It gives back
+// the end of the last complete record, the error wraps errTornRecord when a torn record
+// follows it.
+func (stm *STM) replay(file File, codec Codec, memCells map[string]*memoryCell, from uint64) (int64, error) {
+	r := bufio.NewReader(file)
+
+	var offset int64 // the end of the last complete record
+	for {
+		payload, err := readFrame(r)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, fmt.Errorf("%w: %v", errTornRecord, err)
+		}
+		offset += int64(frameHeaderSize + len(payload))
+
+		version, entries, err := decodeRecord(payload)
+		if err != nil {
+			return offset, err
+		}
+		if version < from {
+			continue // the record is already in the checkpoint
+		}
+		for _, e := range entries {
+			value, err := codec.Decode(e.typ, e.data)
+			if err != nil {
+				return offset, fmt.Errorf("stm: decoding memory cell %s at version %d: %w", e.id, version, err)
+			}
+			memCell, ok := memCells[e.id]
+			if !ok {
+				memCell = newMemCell(stm, value)
+				memCell.id = e.id
+				memCells[e.id] = memCell
+				stm.memory = append(stm.memory, memCell)
+			}
+			memCell.name = e.name
+			memCell.data = value
+			memCell.lastVersion = version
+		}
+		if version > stm.version {
+			stm.version = version
+		}
+	}
+}
+
+// reclaim gives back the recovered or replicated memory cell with the `name`, if it
+// hasn't been reclaimed yet, or the replicated memory cell with the name on a read-only
+// STM, every time.
+// The caller must hold the commit lock.
+func (stm *STM) reclaim(name string) (*memoryCell, bool) {
+	if stm.readOnly {
+		if memCell, ok := stm.lookup(name); ok {
+			return memCell, true
+		}
+	}
+	memCell, ok := stm.unclaimed[name]
+	if ok {
+		delete(stm.unclaimed, name)
+	}
+	return memCell, ok
+}
+
+// logCells appends a record of the memory cells and their new contents at the `version`
+// to the write-ahead log, if the STM is persisted. The caller must hold the commit lock.
+// The memory cells whose contents the codec doesn't encode are left out.
+func (stm *STM) logCells(version uint64, memCells []*memoryCell, values []Value) error {
+	if stm.wal == nil {
+		return nil
+	}
+
+	entries, err := stm.wal.encodeEntries(memCells, values)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+	return stm.wal.append(encodeRecord(version, entries))
+}
+
+// encodeEntries encodes the memory cells and their `values` into the entries of a record.
+// The memory cells whose values the codec doesn't encode are left out, and reported the
+// first time. The caller must hold the commit lock.
+func (w *wal) encodeEntries(memCells []*memoryCell, values []Value) ([]walEntry, error) {
+	entries := make([]walEntry, 0, len(memCells))
+	for i, memCell := range memCells {
+		if memCell.compute != nil {
+			continue // computed TVars are computed again after recovery
+		}
+		typ, data, err := w.opts.codec.Encode(values[i])
+		if errors.Is(err, ErrNoCodec) {
+			if !w.skipped[memCell] {
+				w.skipped[memCell] = true
+				log.Printf("stm: memory cell %s is not persisted, no codec for its %T value", memCell.label(), values[i])
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stm: encoding memory cell %s: %w", memCell.label(), err)
+		}
+		entries = append(entries, walEntry{id: memCell.id, name: memCell.name, typ: typ, data: data})
+	}
+	return entries, nil
+}
+
+// logCommit logs the commit of the memory cells created and the new values written by
+// a transaction, at the next version of the STM. The caller must hold the commit lock.
+func (stm *STM) logCommit(newValues map[*memoryCell]Value, newCells []*memoryCell) error {
+	if stm.wal == nil {
+		return nil
+	}
+
+	memCells := make([]*memoryCell, 0, len(newCells)+len(newValues))
+	values := make([]Value, 0, len(newCells)+len(newValues))
+	for _, memCell := range newCells {
+		memCells, values = append(memCells, memCell), append(values, memCell.data)
+	}
+	for memCell, value := range newValues {
+		memCells, values = append(memCells, memCell), append(values, value)
+	}
+	return stm.logCells(stm.version+1, memCells, values)
+}
+
+// logOffset gives the end of the write-ahead log, the records appended after it can be
+// taken back with unlog. The caller must hold the commit lock.
+func (stm *STM) logOffset() int64 {
+	if stm.wal == nil {
+		return 0
+	}
+	stm.wal.lock.Lock()
+	defer stm.wal.lock.Unlock()
+	return stm.wal.size
+}
+
+// unlog takes back the records appended to the write-ahead log after the `offset`, the
+// records of a commit aborted after it was logged. When they can't be taken back the
+// log is unusable, the commits after it are aborted. The caller must hold the commit
+// lock.
+func (stm *STM) unlog(offset int64) {
+	if stm.wal == nil {
+		return
+	}
+	w := stm.wal
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err != nil {
+		return // nothing was appended since the log failed
+	}
+	if err := w.cut(offset); err != nil {
+		log.Println(err)
+		w.err = err
+	}
+}
+
+// append appends the record to the log and flushes it when the sync policy asks for it.
+func (w *wal) append(payload []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	if err := checkFrame(payload); err != nil {
+		return err
+	}
+
+	frame := encodeFrame(payload)
+	if _, err := w.file.Write(frame); err != nil {
+		w.err = fmt.Errorf("stm: writing the write-ahead log: %w", err)
+		w.cut(w.size) // the torn record isn't replayed, but the log stays unusable
+		return w.err
+	}
+	offset := w.size
+	w.size += int64(len(frame))
+	w.dirty = true
+
+	if w.opts.sync == SyncAlways {
+		if err := w.sync(); err != nil {
+			// the record may reach the disk all the same, it mustn't be replayed as a commit
+			w.cut(offset)
+			return err
+		}
+	}
+	return nil
+}
+
+// cut cuts the log off at the `offset`, the end of a record, and flushes it. The records
+// after it are gone, it is how the record of a commit that was aborted after all is
+// taken back. The caller must hold the lock of the log.
+func (w *wal) cut(offset int64) error {
+	err := w.file.Truncate(offset)
+	if err == nil {
+		_, err = w.file.Seek(offset, io.SeekStart)
+	}
+	if err == nil {
+		err = w.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("stm: cutting off the write-ahead log: %w", err)
+	}
+	w.size = offset
+	w.dirty = false
+	return nil
+}
+
+// sync flushes the log to stable storage. The caller must hold the lock of the log.
+func (w *wal) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		w.err = fmt.Errorf("stm: flushing the write-ahead log: %w", err)
+		return w.err
+	}
+	w.dirty = false
+	return nil
+}
+
+// syncPeriodically flushes the log every sync interval until the log is closed.
+func (w *wal) syncPeriodically() {
+	ticker := time.NewTicker(w.opts.syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.lock.Lock()
+			if w.err == nil {
+				if err := w.sync(); err != nil {
+					log.Println(err)
+				}
+			}
+			w.lock.Unlock()
+		}
+	}
+}
+
+// close flushes and closes the log.
+func (w *wal) close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err == ErrClosed {
+		return nil
+	}
+	close(w.done)
+
+	err := w.err
+	if err == nil {
+		err = w.sync()
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.err = ErrClosed
+	return err
+}
+
+// ------------------------------------------------------------------------
+
+// frameHeaderSize is the size of the header of a record's frame -- length and CRC.
+const frameHeaderSize = 8
+
+// maxFrameSize is the size of the largest record, checkpoints included. No record over
+// it is ever written, so a longer length read back is the length of a torn record.
+const maxFrameSize = 1 << 30
+
+// crcTable is the table of the CRCs of the records.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// encodeFrame frames the payload of a record with its length and CRC. The payload must
+// not be over maxFrameSize, see checkFrame.
+func encodeFrame(payload []byte) []byte {
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	return append(frame, payload...)
+}
+
+// checkFrame checks the payload of a record fits in a frame.
+func checkFrame(payload []byte) error {
+	if len(payload) > maxFrameSize {
+		return fmt.Errorf("stm: record of %d bytes, over the limit of %d", len(payload), maxFrameSize)
+	}
+	return nil
+}
+
+// readFrame reads the payload of the next record. It returns io.EOF at the end of the
+// log and an error for a torn or corrupt record.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [frameHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err // io.EOF when there are no more records
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("record of %d bytes, over the limit of %d", size, maxFrameSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return payload, nil
+}
+
+// encodeRecord encodes a record of the log, the version followed by the entries.
+func encodeRecord(version uint64, entries []walEntry) []byte {
+	buf := binary.AppendUvarint(nil, version)
+	buf = binary.AppendUvarint(buf, uint64(len(entries)))
+	for _, e := range entries {
+		buf = appendBytes(buf, []byte(e.id))
+		buf = appendBytes(buf, []byte(e.name))
+		buf = appendBytes(buf, []byte(e.typ))
+		buf = appendBytes(buf, e.data)
+	}
+	return buf
+}
+
+// decodeRecord decodes a record encoded by encodeRecord.
+func decodeRecord(buf []byte) (version uint64, entries []walEntry, err error) {
+	errCorrupt := errors.New("stm: corrupt record in the write-ahead log")
+
+	version, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return 0, nil, errCorrupt
+	}
+	buf = buf[n:]
+	count, n := binary.Uvarint(buf)
+	if n <= 0 || count > uint64(len(buf)) {
+		return 0, nil, errCorrupt
+	}
+	buf = buf[n:]
+
+	entries = make([]walEntry, count)
+	for i := range entries {
+		var fields [4][]byte
+		for j := range fields {
+			if fields[j], buf = readBytes(buf); fields[j] == nil {
+				return 0, nil, errCorrupt
+			}
+		}
+		entries[i] = walEntry{id: string(fields[0]), name: string(fields[1]), typ: string(fields[2]), data: fields[3]}
+	}
+	return version, entries, nil
+}
+
+// appendBytes appends the length prefixed `b` to the `buf`.
+func appendBytes(buf, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// readBytes reads the length prefixed bytes at the start of the `buf` and gives back the
+// rest of it. The bytes are nil when the `buf` is too short.
+func readBytes(buf []byte) ([]byte, []byte) {
+	size, n := binary.Uvarint(buf)
+	if n <= 0 || size > uint64(len(buf)-n) {
+		return nil, buf
+	}
+	return buf[n : n+int(size) : n+int(size)], buf[n+int(size):]
+}
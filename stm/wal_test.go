@@ -0,0 +1,241 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// wal_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 20:28:11 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:22:22 GMT-0700 (PDT)
+//
+
+package stm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// failingFS is a FileSystem whose files fail their next Sync when told to.
+type failingFS struct {
+	failSync bool // the next Sync fails
+}
+
+func (fs *failingFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	file, err := OSFileSystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &failingFile{File: file, fs: fs}, nil
+}
+
+func (fs *failingFS) Rename(oldpath, newpath string) error {
+	return OSFileSystem.Rename(oldpath, newpath)
+}
+
+func (fs *failingFS) Remove(name string) error { return OSFileSystem.Remove(name) }
+
+// failingFile is a File of a failingFS.
+type failingFile struct {
+	File
+	fs *failingFS
+}
+
+func (f *failingFile) Sync() error {
+	if f.fs.failSync {
+		f.fs.failSync = false
+		return errors.New("sync failed")
+	}
+	return f.File.Sync()
+}
+
+func TestOpenCutsOffARecordOverTheLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stm.wal")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := s.NewNamedTVar("balance", counterValue(1))
+	s.Do(func(tx *Transaction) bool { return tx.Write(balance, counterValue(2)) })
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a torn record whose length was garbled, it must not be allocated
+	var header [frameHeaderSize]byte
+	binary.BigEndian.PutUint32(header[0:4], 0xfffffff0)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(append(header[:], "garbage"...))
+	f.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.NewNamedTVar("balance", counterValue(0)).(*memoryCell).read(); got != counterValue(2) {
+		t.Errorf("recovered balance %v, want 2", got)
+	}
+	if after, _ := os.Stat(path); after.Size() != info.Size() {
+		t.Errorf("log of %d bytes after recovery, want %d", after.Size(), info.Size())
+	}
+}
+
+func TestFailedSyncIsTakenBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stm.wal")
+	fs := new(failingFS)
+	s, err := Open(path, WithFileSystem(fs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance := s.NewNamedTVar("balance", counterValue(1))
+
+	fs.failSync = true
+	if err := s.Do(func(tx *Transaction) bool { return tx.Write(balance, counterValue(2)) }); err == nil {
+		t.Error("the commit succeeded despite the failed sync")
+	}
+	s.Close() // the log is unusable after the failed sync, its record is taken back
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.NewNamedTVar("balance", counterValue(0)).(*memoryCell).read(); got != counterValue(1) {
+		t.Errorf("recovered balance %v, want 1", got)
+	}
+}
+
+func TestOpenDropsTheUnnamedCells(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stm.wal")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.NewTVar(counterValue(1))
+	s.NewNamedTVar("balance", counterValue(2))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := captureLog(t)
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.memory) != 1 || s.memory[0].name != "balance" {
+		t.Fatalf("recovered %d memory cells, want the named one only", len(s.memory))
+	}
+	if !strings.Contains(out.String(), "dropped 1 unnamed memory cells") {
+		t.Errorf("Open didn't report the dropped memory cell, it logged %q", out.String())
+	}
+	if err := s.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if s, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if len(s.memory) != 1 {
+		t.Errorf("recovered %d memory cells from the checkpoint, want 1", len(s.memory))
+	}
+	if out.Len() != 0 {
+		t.Errorf("Open of the checkpoint logged %q, want nothing dropped", out.String())
+	}
+}
+
+// captureLog captures the output of the standard logger until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	out := new(bytes.Buffer)
+	writer := log.Writer()
+	log.SetOutput(out)
+	t.Cleanup(func() { log.SetOutput(writer) })
+	return out
+}
+
+func TestCreatePanicsWhenItCantBeLogged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stm.wal")
+	fs := new(failingFS)
+	s, err := Open(path, WithFileSystem(fs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fs.failSync = true
+	version := s.Version()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("NewNamedTVar created a memory cell that couldn't be logged")
+			}
+		}()
+		s.NewNamedTVar("balance", counterValue(1))
+	}()
+	if s.Version() != version {
+		t.Errorf("the failed creation advanced the version from %d to %d", version, s.Version())
+	}
+	if _, ok := s.Lookup("balance"); ok {
+		t.Error("the memory cell that couldn't be logged was added to the STM")
+	}
+}
+
+func TestValueWithoutACodecIsReportedOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stm.wal")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	out := captureLog(t)
+	tVar := s.NewNamedTVar("opaque", opaque{1})
+	set(s, tVar, opaque{2})
+	if n := strings.Count(out.String(), "memory cell opaque is not persisted"); n != 1 {
+		t.Errorf("the memory cell without a codec was reported %d times, want once:\n%s", n, out.String())
+	}
+}
@@ -0,0 +1,158 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// watch.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 19:41:43 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 19:41:43 GMT-0700 (PDT)
+//
+
+package stm
+
+import "sync"
+
+// Watcher receives the contents of a memory cell every time a committed transaction
+// changes them. The changes are delivered after the commit, outside the commit lock.
+// A watcher that is slow to receive only gets the latest contents, the changes it
+// missed in between are coalesced.
+type Watcher struct {
+	stm     *STM        // the STM the watched memory cell belongs to
+	memCell *memoryCell // the watched memory cell
+	changes chan Value  // the latest contents not yet received, at most one
+	version uint64      // the version of the latest change delivered
+	stopped bool        // flag showing if the watcher has been stopped
+	lock    *sync.Mutex // guards the delivery of the changes
+}
+
+// cellChange is a change to a watched memory cell made by a commit.
+type cellChange struct {
+	memCell *memoryCell // the changed memory cell
+	value   Value       // the new contents of the memory cell
+	version uint64      // the version of the STM after the commit
+}
+
+// Watch starts watching the memory cell referenced by the `tVar`. The changes are
+// received from the watcher's Changes channel until the watcher is stopped.
+func (stm *STM) Watch(tVar TVar) *Watcher {
+	w := new(Watcher)
+	w.stm = stm
+	w.memCell = tVar.(*memoryCell)
+	w.changes = make(chan Value, 1)
+	w.lock = new(sync.Mutex)
+
+	stm.watchLock.Lock()
+	defer stm.watchLock.Unlock()
+	stm.watchers[w.memCell] = append(stm.watchers[w.memCell], w)
+	return w
+}
+
+// WatchFunc starts watching the memory cell referenced by the `tVar` and calls `fn` with
+// the contents of the memory cell for every change, until the watcher is stopped.
+// The `fn` is called from a goroutine of its own, one change at a time.
+func (stm *STM) WatchFunc(tVar TVar, fn func(Value)) *Watcher {
+	w := stm.Watch(tVar)
+	go func() {
+		for value := range w.changes {
+			fn(value)
+		}
+	}()
+	return w
+}
+
+// isWatched checks if the memory cell has any watchers.
+func (stm *STM) isWatched(memCell *memoryCell) bool {
+	stm.watchLock.RLock()
+	defer stm.watchLock.RUnlock()
+	return len(stm.watchers[memCell]) > 0
+}
+
+// notifyWatchers delivers the changes made by a commit to the watchers of the memory cells.
+func (stm *STM) notifyWatchers(changes []cellChange) {
+	for _, change := range changes {
+		stm.watchLock.RLock()
+		watchers := stm.watchers[change.memCell]
+		stm.watchLock.RUnlock()
+
+		for _, w := range watchers {
+			w.deliver(change.value.MakeCopy(), change.version)
+		}
+	}
+}
+
+// Changes gives the channel the changes are received from. It is closed when the
+// watcher is stopped.
+func (w *Watcher) Changes() <-chan Value {
+	return w.changes
+}
+
+// Stop stops the watcher, no more changes are delivered to it.
+func (w *Watcher) Stop() {
+	w.stm.watchLock.Lock()
+	watchers := w.stm.watchers[w.memCell]
+	for i, ww := range watchers {
+		if ww == w {
+			watchers = append(watchers[:i:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(watchers) == 0 {
+		delete(w.stm.watchers, w.memCell)
+	} else {
+		w.stm.watchers[w.memCell] = watchers
+	}
+	w.stm.watchLock.Unlock()
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.stopped {
+		w.stopped = true
+		close(w.changes)
+	}
+}
+
+// deliver hands the contents of the memory cell after the commit `version` to the
+// watcher, replacing the contents it has not received yet. Changes arriving out of
+// order from concurrent commits are dropped when a later change was already delivered.
+func (w *Watcher) deliver(value Value, version uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.stopped || version <= w.version {
+		return
+	}
+	w.version = version
+
+	select {
+	case <-w.changes: // coalesce with the change not yet received
+	default:
+	}
+	w.changes <- value // never blocks, the channel was drained and only we send
+}
@@ -0,0 +1,129 @@
+//
+//  BSD 3-Clause License
+//
+// Copyright (c) 2018, Sidharth Mishra
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//  list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//  this list of conditions and the following disclaimer in the documentation
+//  and/or other materials provided with the distribution.
+//
+// * Neither the name of the copyright holder nor the names of its
+//  contributors may be used to endorse or promote products derived from
+//  this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+// watch_test.go
+// @author Sidharth Mishra
+// @created Sun Oct 18 2026 21:03:00 GMT-0700 (PDT)
+// @last-modified Sun Oct 18 2026 21:03:00 GMT-0700 (PDT)
+//
+
+package stm
+
+import "testing"
+
+// set commits the `value` into the memory cell referenced by the `tVar`. The changes
+// are delivered to the watchers by the time it returns.
+func set(s *STM, tVar TVar, value Value) {
+	s.Do(func(t *Transaction) bool { return t.Write(tVar, value) })
+}
+
+// expectNoChange checks the watcher has no change to receive.
+func expectNoChange(t *testing.T, w *Watcher, after string) {
+	t.Helper()
+	select {
+	case value, ok := <-w.Changes():
+		if ok {
+			t.Errorf("received %v after %s, want nothing", value, after)
+		}
+	default:
+	}
+}
+
+func TestWatchReceivesTheChanges(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(1))
+	w := s.Watch(tVar)
+	defer w.Stop()
+
+	set(s, tVar, counterValue(2))
+	if value := <-w.Changes(); value != counterValue(2) {
+		t.Errorf("received %v, want 2", value)
+	}
+
+	set(s, tVar, counterValue(2))
+	expectNoChange(t, w, "writing the same contents")
+
+	other := s.NewTVar(counterValue(1))
+	set(s, other, counterValue(3))
+	expectNoChange(t, w, "changing another memory cell")
+}
+
+func TestWatchCoalescesTheChangesNotReceived(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(0))
+	w := s.Watch(tVar)
+	defer w.Stop()
+
+	for i := 1; i <= 3; i++ {
+		set(s, tVar, counterValue(i))
+	}
+	if value := <-w.Changes(); value != counterValue(3) {
+		t.Errorf("received %v, want the latest contents 3", value)
+	}
+	expectNoChange(t, w, "receiving the latest contents")
+}
+
+func TestStoppedWatcherReceivesNothing(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(0))
+	w := s.Watch(tVar)
+	kept := s.Watch(tVar)
+	defer kept.Stop()
+
+	w.Stop()
+	w.Stop() // stopping twice is harmless
+	set(s, tVar, counterValue(1))
+	if value, ok := <-w.Changes(); ok {
+		t.Errorf("a stopped watcher received %v", value)
+	}
+	if value := <-kept.Changes(); value != counterValue(1) {
+		t.Errorf("the other watcher received %v, want 1", value)
+	}
+}
+
+func TestWatchFunc(t *testing.T) {
+	s := New()
+	tVar := s.NewTVar(counterValue(0))
+
+	received := make(chan Value)
+	w := s.WatchFunc(tVar, func(value Value) { received <- value })
+	set(s, tVar, counterValue(1))
+	if value := <-received; value != counterValue(1) {
+		t.Errorf("fn was called with %v, want 1", value)
+	}
+	w.Stop()
+	set(s, tVar, counterValue(2))
+	select {
+	case value := <-received:
+		t.Errorf("fn was called with %v after the watcher was stopped", value)
+	default:
+	}
+}